@@ -0,0 +1,145 @@
+package engine
+
+import (
+	"fmt"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"sort"
+)
+
+// commitGraph links the commits of the linear history by ancestry. Linear
+// history places branches one after the other, so neighbouring indices need
+// not be related where a line starts.
+type commitGraph struct {
+	// parents lists the parent indices of every commit, first parent first
+	parents  [][]int
+	children [][]int
+}
+
+func loadCommitGraph(repo *git.Repository, history *blobHistory) (*commitGraph, error) {
+	indices := make(map[plumbing.Hash]int, len(history.Commits))
+	for index, commit := range history.Commits {
+		indices[commit.Hash] = index
+	}
+
+	graph := &commitGraph{
+		parents:  make([][]int, len(history.Commits)),
+		children: make([][]int, len(history.Commits)),
+	}
+	for index, commit := range history.Commits {
+		object, err := repo.CommitObject(commit.Hash)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get commit %s: %v", commit.Hash, err)
+		}
+		for _, parent := range object.ParentHashes {
+			parentIndex, ok := indices[parent]
+			if !ok {
+				continue
+			}
+			graph.parents[index] = append(graph.parents[index], parentIndex)
+			graph.children[parentIndex] = append(graph.children[parentIndex], index)
+		}
+	}
+	return graph, nil
+}
+
+// components groups the commits in the ranges into sets connected by
+// ancestry, each sorted oldest first. The groups are ordered by their oldest
+// commit.
+func (g *commitGraph) components(ranges []commitRange) [][]int {
+	member := make(map[int]bool)
+	for _, r := range ranges {
+		for index := r.Start; index <= r.End; index++ {
+			member[index] = true
+		}
+	}
+
+	visited := make(map[int]bool, len(member))
+	var groups [][]int
+	for _, r := range ranges {
+		for start := r.Start; start <= r.End; start++ {
+			if visited[start] {
+				continue
+			}
+			var group []int
+			stack := []int{start}
+			visited[start] = true
+			for len(stack) > 0 {
+				index := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				group = append(group, index)
+				for _, next := range append(append([]int{}, g.parents[index]...), g.children[index]...) {
+					if member[next] && !visited[next] {
+						visited[next] = true
+						stack = append(stack, next)
+					}
+				}
+			}
+			sort.Ints(group)
+			groups = append(groups, group)
+		}
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i][0] < groups[j][0]
+	})
+	return groups
+}
+
+// firstConsistentAncestor follows first parents from index as long as the
+// commits stay consistent and returns the oldest one reached.
+func (g *commitGraph) firstConsistentAncestor(index int, consistent map[int]bool) int {
+	for len(g.parents[index]) > 0 && consistent[g.parents[index][0]] {
+		index = g.parents[index][0]
+	}
+	return index
+}
+
+// nextChange walks forward from anchor through consistent descendants and
+// returns the earliest descendant that is no longer consistent, i.e. where an
+// observed file changes, or -1 if there is none. Starting at the anchor keeps
+// the walk on its branch. The count includes every consistent commit from
+// source, the first consistent ancestor of anchor, up to the change.
+func (g *commitGraph) nextChange(source, anchor int, consistent map[int]bool) (int, int) {
+	reached := map[int]bool{anchor: true}
+	for index := anchor; index != source; {
+		index = g.parents[index][0]
+		reached[index] = true
+	}
+
+	queue := []int{anchor}
+	next := -1
+	for len(queue) > 0 {
+		index := queue[0]
+		queue = queue[1:]
+		for _, child := range g.children[index] {
+			switch {
+			case reached[child]:
+			case consistent[child]:
+				reached[child] = true
+				queue = append(queue, child)
+			case next == -1 || child < next:
+				next = child
+			}
+		}
+	}
+
+	if next == -1 {
+		return -1, len(reached)
+	}
+
+	// Only count the commits leading up to the change
+	counted := make(map[int]bool)
+	stack := []int{next}
+	for len(stack) > 0 {
+		index := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, parent := range g.parents[index] {
+			if reached[parent] && !counted[parent] {
+				counted[parent] = true
+				stack = append(stack, parent)
+			}
+		}
+	}
+	return next, len(counted)
+}
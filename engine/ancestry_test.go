@@ -0,0 +1,166 @@
+package engine
+
+import (
+	"reflect"
+	"testing"
+)
+
+// testGraph builds a commit graph from the parent indices of every commit.
+func testGraph(parents [][]int) *commitGraph {
+	graph := &commitGraph{
+		parents:  parents,
+		children: make([][]int, len(parents)),
+	}
+	for index, list := range parents {
+		for _, parent := range list {
+			graph.children[parent] = append(graph.children[parent], index)
+		}
+	}
+	return graph
+}
+
+func testConsistent(indices ...int) map[int]bool {
+	consistent := make(map[int]bool, len(indices))
+	for _, index := range indices {
+		consistent[index] = true
+	}
+	return consistent
+}
+
+// Two branches forked from 0: 1 and 3 on one, 2, 4 and 5 on the other
+var forkedParents = [][]int{{}, {0}, {0}, {1}, {2}, {4}}
+
+func TestComponents(t *testing.T) {
+	tests := []struct {
+		name    string
+		parents [][]int
+		ranges  []commitRange
+		want    [][]int
+	}{
+		{
+			name:    "linear",
+			parents: [][]int{{}, {0}, {1}, {2}},
+			ranges:  []commitRange{{1, 3}},
+			want:    [][]int{{1, 2, 3}},
+		},
+		{
+			name:    "forked branches joined at their base",
+			parents: forkedParents,
+			ranges:  []commitRange{{0, 2}, {4, 4}},
+			want:    [][]int{{0, 1, 2, 4}},
+		},
+		{
+			name:    "separated by an inconsistent commit",
+			parents: [][]int{{}, {0}, {1}},
+			ranges:  []commitRange{{0, 0}, {2, 2}},
+			want:    [][]int{{0}, {2}},
+		},
+		{
+			name:    "unrelated roots",
+			parents: [][]int{{}, {0}, {}, {2}},
+			ranges:  []commitRange{{0, 3}},
+			want:    [][]int{{0, 1}, {2, 3}},
+		},
+		{
+			name:    "merge",
+			parents: [][]int{{}, {0}, {}, {1, 2}},
+			ranges:  []commitRange{{1, 3}},
+			want:    [][]int{{1, 2, 3}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := testGraph(test.parents).components(test.ranges); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("components(%v) = %v, want %v", test.ranges, got, test.want)
+			}
+		})
+	}
+}
+
+func TestFirstConsistentAncestor(t *testing.T) {
+	tests := []struct {
+		name       string
+		parents    [][]int
+		index      int
+		consistent map[int]bool
+		want       int
+	}{
+		{"own branch", forkedParents, 4, testConsistent(0, 1, 2, 4), 0},
+		{"stops at inconsistent parent", forkedParents, 5, testConsistent(1, 4, 5), 4},
+		{"root", forkedParents, 0, testConsistent(0), 0},
+		{"follows first parent of merge", [][]int{{}, {}, {0, 1}}, 2, testConsistent(0, 1, 2), 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := testGraph(test.parents).firstConsistentAncestor(test.index, test.consistent); got != test.want {
+				t.Errorf("firstConsistentAncestor(%d) = %d, want %d", test.index, got, test.want)
+			}
+		})
+	}
+}
+
+func TestNextChange(t *testing.T) {
+	tests := []struct {
+		name       string
+		parents    [][]int
+		source     int
+		anchor     int
+		consistent map[int]bool
+		wantNext   int
+		wantCount  int
+	}{
+		{
+			name:       "stays on the anchor's branch",
+			parents:    forkedParents,
+			source:     0,
+			anchor:     4,
+			consistent: testConsistent(0, 1, 2, 4),
+			wantNext:   5,
+			wantCount:  3,
+		},
+		{
+			name:       "other branch changes first",
+			parents:    forkedParents,
+			source:     0,
+			anchor:     1,
+			consistent: testConsistent(0, 1, 2, 4),
+			wantNext:   3,
+			wantCount:  2,
+		},
+		{
+			name:       "no change",
+			parents:    [][]int{{}, {0}, {1}},
+			source:     0,
+			anchor:     2,
+			consistent: testConsistent(0, 1, 2),
+			wantNext:   -1,
+			wantCount:  3,
+		},
+		{
+			name:       "consistent descendants count",
+			parents:    [][]int{{}, {0}, {1}, {2}},
+			source:     0,
+			anchor:     0,
+			consistent: testConsistent(0, 1, 2),
+			wantNext:   3,
+			wantCount:  3,
+		},
+		{
+			name:       "merge only counts its reached parents",
+			parents:    [][]int{{}, {0}, {0}, {1, 2}},
+			source:     0,
+			anchor:     1,
+			consistent: testConsistent(0, 1, 2),
+			wantNext:   3,
+			wantCount:  2,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			next, count := testGraph(test.parents).nextChange(test.source, test.anchor, test.consistent)
+			if next != test.wantNext || count != test.wantCount {
+				t.Errorf("nextChange(%d, %d) = %d, %d, want %d, %d", test.source, test.anchor, next, count, test.wantNext, test.wantCount)
+			}
+		})
+	}
+}
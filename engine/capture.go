@@ -0,0 +1,350 @@
+package engine
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"github.com/gocolly/colly"
+	"go-find-version/utils"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// capturedResponse is a response recorded before the run, by a browser, an
+// intercepting proxy or a mirroring tool.
+type capturedResponse struct {
+	URL        string
+	StatusCode int
+	Headers    http.Header
+	Body       []byte
+	// File is set for mirrored files, which are only read when needed
+	File string
+	Time time.Time
+	// Decoded bodies no longer have the Content-Encoding their headers name
+	Decoded bool
+}
+
+// checkCapturedHashes replaces checkFileHashes when the responses were
+// captured beforehand. Files are mapped to URLs like live requests, and files
+// without a captured response are left out.
+func checkCapturedHashes(files []string, baseURI, capture string, options checkOptions) (*fileCheckResult, error) {
+	utils.PrintInfo("Reading captured responses from " + capture)
+
+	responses, err := readCapture(capture, baseURI)
+	if err != nil {
+		return nil, err
+	}
+
+	result := newFileCheckResult()
+	found := 0
+	for _, file := range files {
+		fullURL, ok := fileURL(baseURI, file, options.Rules)
+		if !ok {
+			continue
+		}
+		response, ok := responses[captureKey(fullURL)]
+		if !ok {
+			continue
+		}
+		found++
+
+		body := response.Body
+		if response.File != "" {
+			body, err = os.ReadFile(response.File)
+			if err != nil {
+				utils.PrintError(err, "Failed to read mirrored file")
+				continue
+			}
+		}
+
+		evidence := fileEvidence{
+			Method:          http.MethodGet,
+			URL:             stripCredentials(response.URL),
+			StatusCode:      response.StatusCode,
+			ContentType:     response.Headers.Get("Content-Type"),
+			Size:            len(body),
+			LastModified:    response.Headers.Get("Last-Modified"),
+			ETag:            response.Headers.Get("ETag"),
+			Server:          response.Headers.Get("Server"),
+			ContentEncoding: response.Headers.Get("Content-Encoding"),
+			Attempts:        1,
+			Timestamp:       response.Time,
+		}
+		if length, err := strconv.ParseInt(response.Headers.Get("Content-Length"), 10, 64); err == nil {
+			evidence.ContentLength = length
+		}
+
+		if response.StatusCode == http.StatusNotModified {
+			result.Statuses[file] = statusNotCaptured
+			result.Evidence[file] = append(result.Evidence[file], evidence)
+			continue
+		}
+		if response.StatusCode < 200 || response.StatusCode >= 300 {
+			result.Statuses[file] = errorStatus(response.StatusCode)
+			result.Evidence[file] = append(result.Evidence[file], evidence)
+			continue
+		}
+
+		if !response.Decoded {
+			r := &colly.Response{Body: body, Headers: &response.Headers}
+			encoding, err := decodeResponse(r)
+			if err != nil {
+				evidence.Error = err.Error()
+				result.Statuses[file] = statusNetworkError
+				result.Evidence[file] = append(result.Evidence[file], evidence)
+				continue
+			}
+			body = r.Body
+			if encoding != "" {
+				result.Encodings[file] = encoding
+			}
+		}
+
+		hash := hashBlob(body)
+		evidence.Hash = hash.String()
+		result.Evidence[file] = append(result.Evidence[file], evidence)
+		result.add(file, body, hash, options)
+	}
+
+	utils.PrintInfo(fmt.Sprintf("Found captured responses for %d of %d files", found, len(files)))
+	return result, nil
+}
+
+// readCapture reads a HAR file, a Burp Suite XML export or a mirrored
+// directory, keyed by captureKey.
+func readCapture(capture, baseURI string) (map[string]capturedResponse, error) {
+	info, err := os.Stat(capture)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open capture: %v", err)
+	}
+
+	var responses []capturedResponse
+	switch {
+	case info.IsDir():
+		responses, err = readMirror(capture, baseURI)
+	case baseURI == "":
+		return nil, fmt.Errorf("the website url is needed to match captured requests")
+	case strings.EqualFold(filepath.Ext(capture), ".har"):
+		responses, err = readHAR(capture)
+	case strings.EqualFold(filepath.Ext(capture), ".xml"):
+		responses, err = readBurp(capture)
+	default:
+		return nil, fmt.Errorf("unknown capture format %s, expected a .har or Burp .xml file or a directory", capture)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Later responses win, unless they failed where an earlier one succeeded
+	result := make(map[string]capturedResponse, len(responses))
+	for _, response := range responses {
+		key := captureKey(response.URL)
+		if previous, ok := result[key]; ok && previous.StatusCode == http.StatusOK && response.StatusCode != http.StatusOK {
+			continue
+		}
+		result[key] = response
+	}
+	return result, nil
+}
+
+// captureKey identifies a URL regardless of scheme, query and escaping, as
+// captures often mix http and https and add cache busting parameters.
+func captureKey(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	return strings.ToLower(u.Host) + "/" + strings.TrimPrefix(u.Path, "/")
+}
+
+type harFile struct {
+	Log struct {
+		Entries []struct {
+			StartedDateTime time.Time `json:"startedDateTime"`
+			Request         struct {
+				Method string `json:"method"`
+				URL    string `json:"url"`
+			} `json:"request"`
+			Response struct {
+				Status  int `json:"status"`
+				Headers []struct {
+					Name  string `json:"name"`
+					Value string `json:"value"`
+				} `json:"headers"`
+				Content struct {
+					Text     *string `json:"text"`
+					Encoding string  `json:"encoding"`
+				} `json:"content"`
+			} `json:"response"`
+		} `json:"entries"`
+	} `json:"log"`
+}
+
+// readHAR reads the GET requests of an HTTP archive. Browsers store bodies
+// decoded, and leave them out for responses they did not keep.
+func readHAR(filename string) ([]capturedResponse, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read HAR file: %v", err)
+	}
+	var har harFile
+	if err := json.Unmarshal(data, &har); err != nil {
+		return nil, fmt.Errorf("failed to parse HAR file: %v", err)
+	}
+
+	var responses []capturedResponse
+	missing := 0
+	for _, entry := range har.Log.Entries {
+		if entry.Request.Method != http.MethodGet {
+			continue
+		}
+		response := capturedResponse{
+			URL:        entry.Request.URL,
+			StatusCode: entry.Response.Status,
+			Headers:    make(http.Header),
+			Time:       entry.StartedDateTime,
+			Decoded:    true,
+		}
+		for _, header := range entry.Response.Headers {
+			response.Headers.Add(header.Name, header.Value)
+		}
+
+		content := entry.Response.Content
+		if content.Text == nil {
+			if response.StatusCode == http.StatusOK {
+				missing++
+				continue
+			}
+		} else if content.Encoding == "base64" {
+			response.Body, err = base64.StdEncoding.DecodeString(*content.Text)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode body of %s: %v", response.URL, err)
+			}
+		} else {
+			response.Body = []byte(*content.Text)
+		}
+		responses = append(responses, response)
+	}
+
+	if missing > 0 {
+		utils.PrintWarning(fmt.Sprintf("%d responses in the HAR file have no stored body", missing))
+	}
+	return responses, nil
+}
+
+type burpItems struct {
+	Items []struct {
+		Time     string `xml:"time"`
+		URL      string `xml:"url"`
+		Method   string `xml:"method"`
+		Response struct {
+			Base64 bool   `xml:"base64,attr"`
+			Data   string `xml:",chardata"`
+		} `xml:"response"`
+	} `xml:"item"`
+}
+
+// readBurp reads a Burp Suite "Save items" XML export. Responses are stored
+// raw, including headers and any content encoding.
+func readBurp(filename string) ([]capturedResponse, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Burp export: %v", err)
+	}
+	var items burpItems
+	if err := xml.Unmarshal(data, &items); err != nil {
+		return nil, fmt.Errorf("failed to parse Burp export: %v", err)
+	}
+
+	var responses []capturedResponse
+	for _, item := range items.Items {
+		if item.Method != http.MethodGet || item.Response.Data == "" {
+			continue
+		}
+		raw := []byte(item.Response.Data)
+		if item.Response.Base64 {
+			raw, err = base64.StdEncoding.DecodeString(strings.TrimSpace(item.Response.Data))
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode response of %s: %v", item.URL, err)
+			}
+		}
+
+		// Burp writes the status line of HTTP/2 and HTTP/3 responses as
+		// "HTTP/2 200", which net/http only reads with a minor version
+		for _, version := range []string{"HTTP/2 ", "HTTP/3 "} {
+			if bytes.HasPrefix(raw, []byte(version)) {
+				raw = append([]byte("HTTP/1.1 "), raw[len(version):]...)
+			}
+		}
+
+		parsed, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(raw)), nil)
+		if err != nil {
+			utils.PrintWarning(fmt.Sprintf("Skipping unparsable response of %s: %v", item.URL, err))
+			continue
+		}
+		body, err := io.ReadAll(parsed.Body)
+		parsed.Body.Close()
+		if err != nil && len(body) == 0 {
+			utils.PrintWarning(fmt.Sprintf("Skipping truncated response of %s: %v", item.URL, err))
+			continue
+		}
+
+		// Burp writes times like "Fri Oct 16 23:21:00 CEST 2026"
+		captured, _ := time.Parse("Mon Jan 02 15:04:05 MST 2006", item.Time)
+		responses = append(responses, capturedResponse{
+			URL:        item.URL,
+			StatusCode: parsed.StatusCode,
+			Headers:    parsed.Header,
+			Body:       body,
+			Time:       captured,
+		})
+	}
+	return responses, nil
+}
+
+// readMirror lists the files of a mirrored website, e.g. made with
+// wget --mirror. Paths are relative to the website url, so pass the host
+// directory of the mirror. Query strings saved in file names are dropped.
+func readMirror(dir, baseURI string) ([]capturedResponse, error) {
+	var responses []capturedResponse
+	err := filepath.WalkDir(dir, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, file)
+		if err != nil {
+			return err
+		}
+		webPath, _, _ := strings.Cut(filepath.ToSlash(rel), "?")
+		fullURL, err := buildFullURL(baseURI, webPath)
+		if err != nil {
+			return err
+		}
+
+		responses = append(responses, capturedResponse{
+			URL:        fullURL,
+			StatusCode: http.StatusOK,
+			Headers:    make(http.Header),
+			File:       file,
+			Decoded:    true,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mirror: %v", err)
+	}
+	return responses, nil
+}
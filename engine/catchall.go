@@ -0,0 +1,110 @@
+package engine
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/gocolly/colly"
+	"go-find-version/utils"
+	"regexp"
+	"strings"
+)
+
+// catchAllSizeTolerance allows for catch-all HTML pages that echo the
+// requested path back.
+const catchAllSizeTolerance = 64
+
+var titlePattern = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+
+// responseFingerprint identifies the page a server returns for paths that do
+// not exist, e.g. the index page of a single page application.
+type responseFingerprint struct {
+	Hash        plumbing.Hash
+	Size        int
+	ContentType string
+	Title       string
+}
+
+func fingerprintResponse(r *colly.Response) responseFingerprint {
+	contentType, _, _ := strings.Cut(r.Headers.Get("Content-Type"), ";")
+	fingerprint := responseFingerprint{
+		Hash:        hashBlob(r.Body),
+		Size:        len(r.Body),
+		ContentType: strings.ToLower(strings.TrimSpace(contentType)),
+	}
+	if match := titlePattern.FindSubmatch(r.Body); match != nil {
+		fingerprint.Title = strings.TrimSpace(string(match[1]))
+	}
+	return fingerprint
+}
+
+func (f responseFingerprint) matches(other responseFingerprint) bool {
+	if f.Hash == other.Hash {
+		return true
+	}
+	if f.ContentType != other.ContentType {
+		return false
+	}
+	if f.Title != "" && f.Title == other.Title {
+		return true
+	}
+	// Small real files such as robots.txt would match short plain text or
+	// JSON error bodies by size alone
+	if f.ContentType != "text/html" {
+		return false
+	}
+	diff := f.Size - other.Size
+	return diff >= -catchAllSizeTolerance && diff <= catchAllSizeTolerance
+}
+
+// detectCatchAll requests a few random paths that cannot exist and
+// fingerprints every successful response. An empty result means the server
+// answers missing files with a proper error status.
+func detectCatchAll(baseURI string) []responseFingerprint {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		utils.PrintError(err, "Failed to generate catch-all probe paths")
+		return nil
+	}
+	random := hex.EncodeToString(token)
+
+	// Static files and pages are often routed differently
+	probes := []string{
+		random,
+		random + ".js",
+		random + ".css",
+		random + "/" + random + ".txt",
+	}
+
+	var baselines []responseFingerprint
+	c := newCollector(false)
+	c.OnResponse(func(r *colly.Response) {
+		if _, err := decodeResponse(r); err != nil {
+			return
+		}
+		baselines = append(baselines, fingerprintResponse(r))
+	})
+
+	for _, probe := range probes {
+		fullURL, err := buildFullURL(baseURI, probe)
+		if err != nil {
+			continue
+		}
+		c.Visit(fullURL)
+	}
+
+	if len(baselines) > 0 {
+		utils.PrintWarning(fmt.Sprintf("Server answered %d of %d requests for missing files, discarding matching responses", len(baselines), len(probes)))
+	}
+	return baselines
+}
+
+func isCatchAll(baselines []responseFingerprint, fingerprint responseFingerprint) bool {
+	for _, baseline := range baselines {
+		if baseline.matches(fingerprint) {
+			return true
+		}
+	}
+	return false
+}
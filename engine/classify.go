@@ -0,0 +1,108 @@
+package engine
+
+import (
+	"path"
+	"strings"
+)
+
+type fileClass string
+
+const (
+	// classServed files are returned verbatim by a typical webserver
+	classServed fileClass = "served"
+	// classExecuted files are run by the server, so their output never
+	// matches the blob
+	classExecuted fileClass = "executed"
+	// classBlocked files are usually denied by server or framework rules
+	classBlocked fileClass = "blocked"
+	classUnknown fileClass = "unknown"
+)
+
+var servedExtensions = map[string]bool{
+	".css": true, ".js": true, ".mjs": true, ".map": true,
+	".html": true, ".htm": true, ".txt": true, ".md": true,
+	".xml": true, ".json": true, ".svg": true,
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".ico": true, ".webp": true, ".bmp": true,
+	".woff": true, ".woff2": true, ".ttf": true, ".eot": true, ".otf": true,
+	".pdf": true, ".swf": true,
+}
+
+var executedExtensions = map[string]bool{
+	".php": true, ".phtml": true, ".php3": true, ".php4": true, ".php5": true, ".php7": true, ".phar": true,
+	".cgi": true, ".pl": true, ".py": true, ".rb": true, ".asp": true, ".aspx": true, ".jsp": true,
+}
+
+// Configuration formats are only returned from directories meant to be public
+var publicOnlyExtensions = map[string]bool{
+	".yml": true, ".yaml": true, ".ini": true, ".conf": true, ".dist": true,
+}
+
+var blockedExtensions = map[string]bool{
+	".inc": true, ".module": true, ".install": true, ".engine": true, ".profile": true, ".theme": true,
+	".twig": true, ".tpl": true, ".sql": true, ".sh": true, ".lock": true, ".env": true,
+}
+
+var blockedDirs = map[string]bool{
+	"test": true, "tests": true, "__tests__": true, "spec": true, "fixtures": true, "node_modules": true,
+}
+
+var publicDirs = map[string]bool{
+	"public": true, "web": true, "pub": true, "static": true, "assets": true, "htdocs": true, "docroot": true, "www": true,
+}
+
+// classifyFile guesses from its path whether a webserver returns the file
+// verbatim, executes it or refuses to serve it.
+func classifyFile(file string) fileClass {
+	segments := strings.Split(strings.TrimPrefix(file, "/"), "/")
+	dirs := segments[:len(segments)-1]
+
+	public := false
+	for i, dir := range dirs {
+		lower := strings.ToLower(dir)
+		switch {
+		case strings.HasPrefix(dir, ".") && dir != ".well-known":
+			return classBlocked
+		case blockedDirs[lower]:
+			return classBlocked
+		case i == 0 && lower == "vendor":
+			// Composer dependencies, as opposed to vendored assets like core/assets/vendor
+			return classBlocked
+		case publicDirs[lower]:
+			public = true
+		}
+	}
+
+	name := segments[len(segments)-1]
+	if strings.HasPrefix(name, ".") {
+		return classBlocked
+	}
+
+	ext := strings.ToLower(path.Ext(name))
+	switch {
+	case executedExtensions[ext]:
+		return classExecuted
+	case blockedExtensions[ext]:
+		return classBlocked
+	case servedExtensions[ext]:
+		return classServed
+	case publicOnlyExtensions[ext]:
+		if public {
+			return classServed
+		}
+		return classBlocked
+	case ext == "":
+		// README, LICENSE, AUTHORS and friends
+		return classServed
+	}
+	return classUnknown
+}
+
+func filterServable(files []string) []string {
+	var servable []string
+	for _, file := range files {
+		if classifyFile(file) == classServed {
+			servable = append(servable, file)
+		}
+	}
+	return servable
+}
@@ -0,0 +1,113 @@
+package engine
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"github.com/andybalholm/brotli"
+	"github.com/gocolly/colly"
+	"io"
+	"strings"
+)
+
+// compressedVariants are pre-compressed siblings a server may hold next to a
+// file, tried in order when the file itself is not served.
+var compressedVariants = []string{".gz", ".br"}
+
+// nextVariant returns the variant to try after the given one, or an empty
+// string once all were tried.
+func nextVariant(variant string) string {
+	if variant == "" {
+		return compressedVariants[0]
+	}
+	for i, candidate := range compressedVariants[:len(compressedVariants)-1] {
+		if candidate == variant {
+			return compressedVariants[i+1]
+		}
+	}
+	return ""
+}
+
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// decodeResponse replaces the response body with its decoded content and
+// returns the content codings that were removed. Gzip bodies may already have
+// been decoded by the HTTP client while the header is kept, so gzip is only
+// decoded if the body still looks compressed.
+func decodeResponse(r *colly.Response) (string, error) {
+	header := r.Headers.Get("Content-Encoding")
+	if header == "" {
+		return "", nil
+	}
+
+	codings := strings.Split(header, ",")
+	body := r.Body
+	var decoded []string
+
+	// Codings are listed in the order they were applied
+	for i := len(codings) - 1; i >= 0; i-- {
+		coding := strings.ToLower(strings.TrimSpace(codings[i]))
+		if coding == "" || coding == "identity" {
+			continue
+		}
+		if (coding == "gzip" || coding == "x-gzip") && !bytes.HasPrefix(body, gzipMagic) {
+			continue
+		}
+
+		result, err := decodeBody(body, coding)
+		if err != nil {
+			return "", fmt.Errorf("failed to decode %s content: %v", coding, err)
+		}
+		body = result
+		decoded = append(decoded, coding)
+	}
+
+	r.Body = body
+	return strings.Join(decoded, ", "), nil
+}
+
+func decodeBody(body []byte, coding string) ([]byte, error) {
+	var reader io.Reader
+	switch coding {
+	case "gzip", "x-gzip":
+		gzipReader, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		defer gzipReader.Close()
+		reader = gzipReader
+	case "deflate":
+		// "deflate" should be zlib wrapped, but raw deflate is common as well
+		zlibReader, err := zlib.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return io.ReadAll(flate.NewReader(bytes.NewReader(body)))
+		}
+		defer zlibReader.Close()
+		reader = zlibReader
+	case "br":
+		reader = brotli.NewReader(bytes.NewReader(body))
+	default:
+		return nil, fmt.Errorf("unsupported content encoding %q", coding)
+	}
+	return io.ReadAll(reader)
+}
+
+// decodeVariant decompresses the body of a pre-compressed variant, unless the
+// server already declared it as content encoding and it was decoded.
+func decodeVariant(body []byte, variant, encoding string) ([]byte, error) {
+	switch variant {
+	case ".gz":
+		if !bytes.HasPrefix(body, gzipMagic) {
+			return body, nil
+		}
+		return decodeBody(body, "gzip")
+	case ".br":
+		if strings.Contains(encoding, "br") {
+			return body, nil
+		}
+		return decodeBody(body, "br")
+	}
+	return body, nil
+}
@@ -3,13 +3,11 @@ package engine
 import (
 	"fmt"
 	"github.com/charmbracelet/lipgloss"
-	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
-	"github.com/go-git/go-git/v5/plumbing/object"
-	"github.com/go-git/go-git/v5/plumbing/storer"
 	"go-find-version/utils"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 )
@@ -20,29 +18,160 @@ type RepoInfo struct {
 }
 
 func Run(args utils.Args) {
-	owner, repoName := getOwnerAndRepoFromUri(args.GitUrl)
+	offline = args.Offline
+
+	if err := configureFetch(args); err != nil {
+		utils.PrintError(err, "Invalid request settings")
+		return
+	}
+
+	profile, err := resolveProfile(&args)
+	if err != nil {
+		utils.PrintError(err, "Failed to load profile")
+		return
+	}
+
+	if args.LookupPath != "" {
+		runLookup(args)
+		return
+	}
+
+	repository, err := cloneRepo(args.GitUrl)
+	if err != nil {
+		utils.PrintError(err, "Failed to clone repository")
+		return
+	}
+
+	links, err := newLinkProvider(repository.locator, args.Forge, args.ForgeUrl)
+	if err != nil {
+		utils.PrintError(err, "Failed to set up commit links")
+		links = noLinks{}
+	}
+
+	filter, err := newFileFilter(args.Include, args.Exclude, args.IncludeFile, args.ExcludeFile)
+	if err != nil {
+		utils.PrintError(err, "Failed to load file patterns")
+		return
+	}
+
+	// Profile rules map the repository to the webroot, while a local
+	// deployment directory holds the whole repository
+	var profileRules rewriteRules
+	if profile != nil && args.Local == "" {
+		profileRules, err = parseRewriteRules(profile.Rewrite)
+		if err != nil {
+			utils.PrintError(err, "Failed to parse profile rewrite rules")
+			return
+		}
+	}
+	userRules, err := parseRewriteRules(args.Rewrite)
+	if err != nil {
+		utils.PrintError(err, "Failed to parse rewrite rules")
+		return
+	}
+	rules := append(append(rewriteRules{}, profileRules...), userRules...)
+
+	normalize, err := parseNormalizer(args.Normalize)
+	if err != nil {
+		utils.PrintError(err, "Failed to parse normalization")
+		return
+	}
 
 	files := []string{}
 
-	if args.EnumerationGitFile == "" {
-		files = iterateRepo(args.GitUrl)
-		saveFiles(files, owner, repoName)
+	if args.EnumerationGitFile == "" && profile != nil && !args.FullScan && args.Local == "" {
+		utils.PrintInfo(fmt.Sprintf("Using the file list of the %s profile", profile.Name))
+		files = filter.Apply(profile.Files)
+	} else if args.EnumerationGitFile == "" {
+		files = iterateRepo(repository, filter)
+		saveFiles(files, repository.locator)
 	} else {
 		loadedFiles, err := loadFiles(args.EnumerationGitFile)
 		if err != nil {
 			utils.PrintError(err, "Failed to load files")
 		} else {
-			files = append(files, loadedFiles...)
+			files = append(files, filter.Apply(loadedFiles)...)
 		}
 	}
 
+	if args.ServableOnly {
+		servable := filterServable(files)
+		utils.PrintInfo(fmt.Sprintf("Skipping %d files that are unlikely to be served verbatim", len(files)-len(servable)))
+		files = servable
+	}
+
 	utils.PrintInfo(fmt.Sprintf("Found %d files that will be checked on the remote server", len(files)))
 
-	fileHashes := checkFileHashes(files, args.WebsiteUrl)
+	if (args.Capture != "" || args.Local != "") && (args.DetectWebroot || args.HeadPrefilter || args.CompressedVariants) {
+		utils.PrintWarning("Webroot detection, size prefiltering and compressed variants need a live website and are skipped")
+		args.DetectWebroot = false
+		args.HeadPrefilter = false
+		args.CompressedVariants = false
+	}
+
+	if args.DetectWebroot {
+		rules, err = detectWebroot(repository, files, args.WebsiteUrl, profileRules, userRules)
+		if err != nil {
+			utils.PrintError(err, "Failed to detect webroot")
+		}
+	}
+	if len(rules) > 0 {
+		utils.PrintInfo("Rewriting paths with " + strings.Join(rules.Specs(), ", "))
+	}
+
+	// Files dropped by the prefilter keep their status and HEAD response
+	skipped := newFileCheckResult()
+	if args.HeadPrefilter {
+		// Normalized and nearest matches differ from the blob, so their size
+		// never matches
+		compareSizes := len(normalize) == 0 && !args.Fuzzy
+		if !compareSizes {
+			utils.PrintWarning("File sizes are not compared with --normalize or --fuzzy, the prefilter only skips missing files")
+		}
+		files, skipped, err = prefilterBySize(repository, files, args.WebsiteUrl, rules, args.CompressedVariants, compareSizes)
+		if err != nil {
+			utils.PrintError(err, "Failed to prefilter files by size")
+		}
+	}
+
+	options := checkOptions{
+		Rules:      rules,
+		Normalize:  normalize,
+		KeepBodies: args.Fuzzy,
+		Variants:   args.CompressedVariants,
+	}
+	var checked *fileCheckResult
+	if args.Local != "" {
+		checked, err = checkLocalHashes(files, args.Local, options)
+		if err != nil {
+			utils.PrintError(err, "Failed to hash local files")
+			return
+		}
+	} else if args.Capture != "" {
+		checked, err = checkCapturedHashes(files, args.WebsiteUrl, args.Capture, options)
+		if err != nil {
+			utils.PrintError(err, "Failed to read captured responses")
+			return
+		}
+	} else {
+		checked = checkFileHashes(files, args.WebsiteUrl, options)
+	}
+	for file, status := range skipped.Statuses {
+		checked.Statuses[file] = status
+		checked.Evidence[file] = append(skipped.Evidence[file], checked.Evidence[file]...)
+	}
+	fileHashes := checked.Hashes
 
 	utils.PrintInfo(fmt.Sprintf("Found %d files on remote server", len(fileHashes)))
 
-	commits, err := findFirstFilesCommits(args.GitUrl, fileHashes)
+	normalizedFiles, err := matchNormalized(repository, checked, normalize)
+	if err != nil {
+		utils.PrintError(err, "Failed to match normalized files")
+	} else if len(normalizedFiles) > 0 {
+		utils.PrintInfo(fmt.Sprintf("%d files only matched after normalization", len(normalizedFiles)))
+	}
+
+	commits, err := findFirstFilesCommits(repository, fileHashes, checked.NormalizedBlobs)
 
 	utils.PrintInfo(fmt.Sprintf("Found %d files in commits", len(commits)))
 
@@ -50,39 +179,120 @@ func Run(args utils.Args) {
 		utils.PrintError(err, "Failed to find first commits")
 	}
 
-	lower, upper, scores, err := findDeploymentRange(args.GitUrl, commits)
+	var nearest map[string]nearestRevision
+	if args.Fuzzy {
+		nearest, err = findNearestRevisions(repository, checked)
+		if err != nil {
+			utils.PrintError(err, "Failed to find nearest revisions")
+		}
+	}
+
+	deployment, err := findDeploymentRange(repository, fileHashes, checked.NormalizedBlobs, nearest)
 
 	if err != nil {
+		// The statuses and evidence are still worth reporting, and none of
+		// the downloaded files matched a commit
 		utils.PrintError(err, "Failed to find deployment range")
+		deployment = &DeploymentRange{}
+		for file := range fileHashes {
+			deployment.Unmatched = append(deployment.Unmatched, file)
+		}
+		sort.Strings(deployment.Unmatched)
 	}
 
-	displayDeploymentInfo(args.GitUrl, lower, upper, scores)
-}
+	checked.resolveStatuses(deployment.Unmatched)
+
+	release, err := findDeploymentRelease(repository, deployment)
 
-func displayDeploymentInfo(repoUri string, lower, upper plumbing.Hash, scores []CommitScore) {
-	repository, err := cloneRepo(repoUri, false)
 	if err != nil {
-		return
+		utils.PrintError(err, "Failed to resolve release tags")
 	}
 
-	repo := repository.repo
-	lowerCommit, _ := repo.CommitObject(lower)
-	upperCommit, _ := repo.CommitObject(upper)
+	var divergence *localDivergence
+	if args.Local != "" && !deployment.Source.IsZero() {
+		divergence, err = findLocalDivergence(repository, deployment.Source, args.Local, files, checked, rules, filter)
+		if err != nil {
+			utils.PrintError(err, "Failed to compare the directory with the source commit")
+		}
+	}
 
-	commitCount := 0
-	commitIter, _ := repo.Log(&git.LogOptions{
-		Order: git.LogOrderCommitterTime,
-	})
-	_ = commitIter.ForEach(func(c *object.Commit) error {
-		if c.Hash == upper {
-			return nil
+	displayFileStatuses(checked.Statuses)
+	if !deployment.Source.IsZero() {
+		displayDeploymentInfo(repository, links, deployment, release)
+	}
+	if divergence != nil {
+		displayLocalDivergence(divergence)
+	}
+
+	if args.Output != "" {
+		report := buildReport(repository, links, args.GitUrl, args.WebsiteUrl, deployment, release)
+		if profile != nil {
+			report.Profile = profile.Name
+		}
+		report.RewriteRules = rules.Specs()
+		report.Normalization = normalize.Names()
+		report.Files = buildReportFiles(checked, args.WebsiteUrl, rules, normalizedFiles)
+		report.Statuses = checked.Statuses
+		report.Evidence = checked.Evidence
+		if args.Local != "" {
+			report.Local = args.Local
+			report.Divergence = divergence
+		} else if args.Capture != "" {
+			report.Capture = args.Capture
+		} else {
+			report.Fetch = buildReportFetch(fetch)
 		}
-		if c.Hash == lower {
-			return storer.ErrStop
+		if err := saveReport(report, args.Output); err != nil {
+			utils.PrintError(err, "Failed to save report")
+		} else {
+			utils.PrintInfo("Report saved to " + args.Output)
 		}
-		commitCount++
-		return nil
-	})
+	}
+}
+
+// resolveProfile looks up the framework profile selected on the command line
+// and fills in its repository unless one was given explicitly.
+func resolveProfile(args *utils.Args) (*frameworkProfile, error) {
+	if args.Profile == "" {
+		return nil, nil
+	}
+
+	profile, err := findProfile(args.Profile)
+	if err != nil {
+		return nil, err
+	}
+
+	if args.GitUrl == "" {
+		args.GitUrl = profile.Repository
+	}
+	return profile, nil
+}
+
+// findDeploymentRelease names the release tags of the deployment. Any tagged
+// commit the webserver files are consistent with may be the deployed one.
+func findDeploymentRelease(repository *CachedRepo, deployment *DeploymentRange) (ReleaseRange, error) {
+	if deployment.Source.IsZero() {
+		return ReleaseRange{}, nil
+	}
+
+	utils.PrintInfo("Resolving release tags")
+
+	var consistent []plumbing.Hash
+	for _, r := range deployment.Consistent {
+		consistent = append(consistent, r.Commits...)
+	}
+	return findReleaseRange(repository.repo, deployment.Source, consistent)
+}
+
+func displayDeploymentInfo(repository *CachedRepo, links linkProvider, deployment *DeploymentRange, release ReleaseRange) {
+	repo := repository.repo
+	lower, upper := deployment.Source, deployment.Next
+	lowerCommit, err := repo.CommitObject(lower)
+	if err != nil {
+		utils.PrintWarning("No deployment source commit found")
+		return
+	}
+	upperCommit, _ := repo.CommitObject(upper)
 
 	headerStyle := lipgloss.NewStyle().
 		Bold(true).
@@ -118,20 +328,38 @@ func displayDeploymentInfo(repoUri string, lower, upper plumbing.Hash, scores []
 		Foreground(lipgloss.Color("#FF69B4")).
 		Bold(true)
 
-	// Generate GitHub links
-	lowerLink := fmt.Sprintf("https://github.com/%s/%s/commit/%s", repository.owner, repository.repoName, lower)
-	upperLink := fmt.Sprintf("https://github.com/%s/%s/commit/%s", repository.owner, repository.repoName, upper)
-	compareLink := fmt.Sprintf("https://github.com/%s/%s/compare/%s..%s", repository.owner, repository.repoName, lower, upper)
+	warningStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("1")).
+		Bold(true)
+
+	tagStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("#98FB98")).
+		Bold(true)
+
+	// Generate forge links, empty when the repository has no web UI
+	renderLink := func(link string) string {
+		if link == "" {
+			return ""
+		}
+		return linkStyle.Render(link)
+	}
+	lowerLink := links.CommitLink(lower)
+	upperLink := links.CommitLink(upper)
+	compareLink := links.CompareLink(lower, upper)
 
 	var output strings.Builder
 
 	output.WriteString(headerStyle.Render("🚀 Deployment Analysis Results\n"))
 
+	// Release info
+	output.WriteString(subHeaderStyle.Render("Release\n"))
+	output.WriteString(fmt.Sprintf("  🏷️  %s\n\n", tagStyle.Render(release.String())))
+
 	// Lower commit info
 	output.WriteString(subHeaderStyle.Render("Webserver State Source\n"))
 	output.WriteString(fmt.Sprintf("  %s %s\n",
 		commitHashStyle.Render(lower.String()[:7]),
-		linkStyle.Render(lowerLink),
+		renderLink(lowerLink),
 	))
 	output.WriteString(fmt.Sprintf("  📝 %s\n", commitMessageStyle.Render(firstLine(lowerCommit.Message))))
 	output.WriteString(fmt.Sprintf("  👤 %s\n", authorStyle.Render(lowerCommit.Author.Name)))
@@ -139,31 +367,67 @@ func displayDeploymentInfo(repoUri string, lower, upper plumbing.Hash, scores []
 
 	// Upper commit info
 	output.WriteString(subHeaderStyle.Render("Next Change Detected\n"))
-	output.WriteString(fmt.Sprintf("  %s %s\n",
-		commitHashStyle.Render(upper.String()[:7]),
-		linkStyle.Render(upperLink),
-	))
-	output.WriteString(fmt.Sprintf("  📝 %s\n", commitMessageStyle.Render(firstLine(upperCommit.Message))))
-	output.WriteString(fmt.Sprintf("  👤 %s\n", authorStyle.Render(upperCommit.Author.Name)))
-	output.WriteString(fmt.Sprintf("  📅 %s\n\n", dateStyle.Render(upperCommit.Author.When.Format(time.RFC1123))))
+	if upperCommit != nil {
+		output.WriteString(fmt.Sprintf("  %s %s\n",
+			commitHashStyle.Render(upper.String()[:7]),
+			renderLink(upperLink),
+		))
+		output.WriteString(fmt.Sprintf("  📝 %s\n", commitMessageStyle.Render(firstLine(upperCommit.Message))))
+		output.WriteString(fmt.Sprintf("  👤 %s\n", authorStyle.Render(upperCommit.Author.Name)))
+		output.WriteString(fmt.Sprintf("  📅 %s\n\n", dateStyle.Render(upperCommit.Author.When.Format(time.RFC1123))))
+	} else {
+		output.WriteString("  No later change to the checked files\n\n")
+	}
 
 	// Commit range info
 	output.WriteString(subHeaderStyle.Render("Deployment Range\n"))
-	output.WriteString(fmt.Sprintf("  Commits between states: %s\n", countStyle.Render(fmt.Sprintf("%d", commitCount))))
-	output.WriteString(fmt.Sprintf("  Compare changes: %s\n\n", linkStyle.Render(compareLink)))
+	if len(deployment.Consistent) == 0 {
+		output.WriteString(fmt.Sprintf("  %s\n", warningStyle.Render("⚠️  No commit is consistent with every observed file, showing the best scoring one")))
+	} else {
+		output.WriteString(fmt.Sprintf("  Commits between states: %s\n", countStyle.Render(fmt.Sprintf("%d", deployment.Commits))))
+		if upperCommit != nil && compareLink != "" {
+			output.WriteString(fmt.Sprintf("  Compare changes: %s\n", renderLink(compareLink)))
+		}
+		output.WriteString(fmt.Sprintf("  Consistent ranges: %s\n", countStyle.Render(fmt.Sprintf("%d", len(deployment.Consistent)))))
+		for _, r := range deployment.Consistent[:min(5, len(deployment.Consistent))] {
+			output.WriteString(fmt.Sprintf("    %s..%s (%d commits)\n",
+				commitHashStyle.Render(r.First.String()[:7]),
+				commitHashStyle.Render(r.Last.String()[:7]),
+				r.Count,
+			))
+		}
+	}
+	if len(deployment.Unmatched) > 0 {
+		output.WriteString(fmt.Sprintf("  Files matching no historical version: %s\n", countStyle.Render(fmt.Sprintf("%d", len(deployment.Unmatched)))))
+	}
+	for _, revision := range deployment.Nearest {
+		output.WriteString(fmt.Sprintf("    %s ≈ %s (%.0f%% similar)\n",
+			revision.Path,
+			commitHashStyle.Render(revision.Blob.String()[:7]),
+			revision.Similarity*100,
+		))
+	}
+	output.WriteString("\n")
 
 	// Top commits
 	output.WriteString(subHeaderStyle.Render("✨ Top Matching Commits\n"))
-	for i, score := range scores[:min(5, len(scores))] {
+	for i, score := range deployment.Scores {
 		commit, _ := repo.CommitObject(score.Hash)
-		commitLink := fmt.Sprintf("https://github.com/%s/%s/commit/%s", repository.owner, repository.repoName, score.Hash)
+		commitLink := links.CommitLink(score.Hash)
 
 		output.WriteString(fmt.Sprintf("\n  %s. %s %s",
 			countStyle.Render(fmt.Sprintf("%d", i+1)),
 			commitHashStyle.Render(score.Hash.String()[:7]),
-			linkStyle.Render(commitLink),
+			renderLink(commitLink),
+		))
+		output.WriteString(fmt.Sprintf("     📁 %s files matched, %s mismatched, %s unknown\n",
+			countStyle.Render(fmt.Sprintf("%d", score.Matched)),
+			countStyle.Render(fmt.Sprintf("%d", score.Mismatched)),
+			countStyle.Render(fmt.Sprintf("%d", score.Unknown)),
 		))
-		output.WriteString(fmt.Sprintf("     📁 %s files matched\n", countStyle.Render(fmt.Sprintf("%d", score.Score))))
+		if score.Nearest > 0 {
+			output.WriteString(fmt.Sprintf("     🔍 %s nearest revision evidence\n", countStyle.Render(fmt.Sprintf("%.2f", score.Nearest))))
+		}
 		output.WriteString(fmt.Sprintf("     💬 %s\n", commitMessageStyle.Render(firstLine(commit.Message))))
 	}
 
@@ -177,13 +441,14 @@ func firstLine(s string) string {
 	return s
 }
 
-func getFilename(owner, repoName string) string {
+func getFilename(locator *RepoLocator) string {
 	today := time.Now().Format("2006-01-02")
-	return fmt.Sprintf("%s-%s-%s-interesting_files.txt", today, owner, repoName)
+	name := strings.ReplaceAll(filepath.ToSlash(locator.CacheKey()), "/", "-")
+	return fmt.Sprintf("%s-%s-interesting_files.txt", today, name)
 }
 
-func saveFiles(interestingFiles []string, owner, repoName string) error {
-	filename := getFilename(owner, repoName)
+func saveFiles(interestingFiles []string, locator *RepoLocator) error {
+	filename := getFilename(locator)
 	file, err := os.Create(filename)
 	if err != nil {
 		return fmt.Errorf("failed to create file: %v", err)
@@ -191,7 +456,7 @@ func saveFiles(interestingFiles []string, owner, repoName string) error {
 	defer file.Close()
 
 	for _, filepath := range interestingFiles {
-		_, err = file.WriteString(filepath + "\n")
+		_, err = file.WriteString(filepath + "\t" + string(classifyFile(filepath)) + "\n")
 		if err != nil {
 			return fmt.Errorf("failed to write file: %v", err)
 		}
@@ -207,6 +472,8 @@ func loadFiles(filename string) ([]string, error) {
 	lines := strings.Split(string(data), "\n")
 	var result []string
 	for _, line := range lines {
+		// Lines are "<path>\t<class>", older files only hold the path
+		line, _, _ = strings.Cut(line, "\t")
 		if line != "" {
 			result = append(result, line)
 		}
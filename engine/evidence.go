@@ -0,0 +1,73 @@
+package engine
+
+import (
+	"github.com/gocolly/colly"
+	"strconv"
+	"time"
+)
+
+// fileEvidence records what the webserver returned for a single URL, so
+// reports can cite exactly what was observed. Retried requests only keep
+// their last attempt.
+type fileEvidence struct {
+	Method        string `json:"method,omitempty"`
+	URL           string `json:"url"`
+	FinalURL      string `json:"final_url,omitempty"`
+	StatusCode    int    `json:"status_code,omitempty"`
+	ContentType   string `json:"content_type,omitempty"`
+	ContentLength int64  `json:"content_length,omitempty"`
+	// Size is the number of body bytes received, before decoding
+	Size            int    `json:"size"`
+	LastModified    string `json:"last_modified,omitempty"`
+	ETag            string `json:"etag,omitempty"`
+	Server          string `json:"server,omitempty"`
+	ContentEncoding string `json:"content_encoding,omitempty"`
+	// Hash is the blob hash of the decoded body
+	Hash     string `json:"hash,omitempty"`
+	Attempts int    `json:"attempts"`
+	// Duration in milliseconds includes the wait for a free request slot
+	Duration  int64     `json:"duration_ms,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// newEvidence captures the response before its body is decoded. err is the
+// error passed to OnError, nil for successful responses.
+func newEvidence(r *colly.Response, err error) fileEvidence {
+	now := time.Now()
+	attempt, _ := r.Ctx.GetAny("attempt").(int)
+	evidence := fileEvidence{
+		URL:        stripCredentials(r.Ctx.Get("url")),
+		StatusCode: r.StatusCode,
+		Size:       len(r.Body),
+		Attempts:   attempt + 1,
+		Timestamp:  now.UTC(),
+	}
+	if started, ok := r.Ctx.GetAny("started").(time.Time); ok {
+		evidence.Duration = now.Sub(started).Milliseconds()
+	}
+	if err != nil {
+		evidence.Error = err.Error()
+	}
+	if r.Request != nil {
+		evidence.Method = r.Request.Method
+	}
+	if r.Request != nil && r.Request.URL != nil {
+		if final := stripCredentials(r.Request.URL.String()); final != evidence.URL {
+			evidence.FinalURL = final
+		}
+	}
+
+	if r.Headers == nil {
+		return evidence
+	}
+	evidence.ContentType = r.Headers.Get("Content-Type")
+	if length, err := strconv.ParseInt(r.Headers.Get("Content-Length"), 10, 64); err == nil {
+		evidence.ContentLength = length
+	}
+	evidence.LastModified = r.Headers.Get("Last-Modified")
+	evidence.ETag = r.Headers.Get("ETag")
+	evidence.Server = r.Headers.Get("Server")
+	evidence.ContentEncoding = r.Headers.Get("Content-Encoding")
+	return evidence
+}
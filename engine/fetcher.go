@@ -0,0 +1,283 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gocolly/colly"
+	"go-find-version/utils"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	minBackoff = time.Second
+	maxBackoff = time.Minute
+)
+
+// fetchSettings control how every request to the webserver is made. They are
+// set from the command line before the first request.
+type fetchSettings struct {
+	Parallelism       int
+	Delay             time.Duration
+	Jitter            time.Duration
+	RequestsPerSecond float64
+	UserAgent         string
+	Headers           http.Header
+	Connection        *connectionSettings
+	Timeout           time.Duration
+	Retries           int
+	RetryBackoff      time.Duration
+}
+
+var fetch = fetchSettings{
+	Parallelism:  5,
+	Delay:        50 * time.Millisecond,
+	UserAgent:    "FileChecker/1.0",
+	Timeout:      10 * time.Second,
+	Retries:      2,
+	RetryBackoff: time.Second,
+}
+
+var throttle = &requestThrottle{}
+
+func configureFetch(args utils.Args) error {
+	if args.Concurrency < 1 {
+		return fmt.Errorf("concurrency must be at least 1")
+	}
+	if args.RequestsPerSecond < 0 {
+		return fmt.Errorf("requests per second must not be negative")
+	}
+	if args.Timeout <= 0 {
+		return fmt.Errorf("timeout must be positive")
+	}
+	if args.Retries < 0 {
+		return fmt.Errorf("retries must not be negative")
+	}
+
+	headers := make(http.Header)
+	for _, header := range args.Headers {
+		name, value, ok := strings.Cut(header, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			return fmt.Errorf("header %q must have the form \"Name: value\"", header)
+		}
+		headers.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+
+	connection, err := newConnectionSettings(connectionOptions{
+		Proxy:       args.Proxy,
+		CABundle:    args.CABundle,
+		Insecure:    args.Insecure,
+		ClientCert:  args.ClientCert,
+		ClientKey:   args.ClientKey,
+		BasicAuth:   args.BasicAuth,
+		BearerToken: args.BearerToken,
+		CookieFile:  args.CookieFile,
+		WebsiteUrl:  args.WebsiteUrl,
+	})
+	if err != nil {
+		return err
+	}
+
+	fetch = fetchSettings{
+		Parallelism:       args.Concurrency,
+		Delay:             args.Delay,
+		Jitter:            args.Jitter,
+		RequestsPerSecond: args.RequestsPerSecond,
+		UserAgent:         args.UserAgent,
+		Headers:           headers,
+		Connection:        connection,
+		Timeout:           args.Timeout,
+		Retries:           args.Retries,
+		RetryBackoff:      args.RetryBackoff,
+	}
+
+	throttle = &requestThrottle{}
+	if fetch.RequestsPerSecond > 0 {
+		throttle.interval = time.Duration(float64(time.Second) / fetch.RequestsPerSecond)
+	}
+	return nil
+}
+
+// newCollector creates a collector that honours the fetch settings. Its
+// callbacks run before any registered by the caller.
+func newCollector(async bool) *colly.Collector {
+	c := colly.NewCollector(
+		colly.Async(async),
+		colly.UserAgent(fetch.UserAgent),
+	)
+
+	c.Limit(&colly.LimitRule{
+		DomainGlob:  "*",
+		Parallelism: fetch.Parallelism,
+		Delay:       fetch.Delay,
+		RandomDelay: fetch.Jitter,
+	})
+	c.SetRequestTimeout(fetch.Timeout)
+
+	if fetch.Connection != nil {
+		c.WithTransport(fetch.Connection.transport)
+		c.SetCookieJar(fetch.Connection.jar)
+	}
+
+	c.OnRequest(func(r *colly.Request) {
+		if fetch.Connection != nil && fetch.Connection.authorization != "" {
+			r.Headers.Set("Authorization", fetch.Connection.authorization)
+		}
+		for name, values := range fetch.Headers {
+			r.Headers.Del(name)
+			for _, value := range values {
+				r.Headers.Add(name, value)
+			}
+		}
+		throttle.wait()
+		r.Ctx.Put("started", time.Now())
+	})
+
+	c.OnResponse(func(r *colly.Response) {
+		throttle.recover()
+	})
+
+	c.OnError(func(r *colly.Response, err error) {
+		if r.StatusCode == http.StatusTooManyRequests || r.StatusCode == http.StatusServiceUnavailable {
+			throttle.slowDown(retryAfter(r.Headers))
+		}
+	})
+
+	return c
+}
+
+// shouldRetry reports whether a failed request hit a transient error and has
+// retries left. Callers that track requests themselves must account for the
+// retry before calling retryRequest.
+func shouldRetry(r *colly.Response, err error) bool {
+	if r.Request == nil || r.Ctx == nil {
+		return false
+	}
+	attempt, _ := r.Ctx.GetAny("attempt").(int)
+	return attempt < fetch.Retries && isTransient(r.StatusCode, err)
+}
+
+// retryRequest waits with exponential backoff, or as long as the server's
+// Retry-After asks, and sends the request again. An error means the retry was
+// not sent, e.g. because the collector's filters rejected it, and none of the
+// request's callbacks will run again.
+func retryRequest(r *colly.Response) error {
+	attempt, _ := r.Ctx.GetAny("attempt").(int)
+	r.Ctx.Put("attempt", attempt+1)
+
+	wait := min(fetch.RetryBackoff<<attempt, maxBackoff)
+	wait = max(wait, min(retryAfter(r.Headers), maxBackoff))
+	time.Sleep(wait)
+
+	if err := r.Request.Retry(); err != nil {
+		r.Ctx.Put("attempt", attempt)
+		return err
+	}
+	return nil
+}
+
+// isTransient reports whether a request may succeed when sent again. Status
+// code 0 means no response was received.
+func isTransient(statusCode int, err error) bool {
+	switch statusCode {
+	case 0:
+	case http.StatusRequestTimeout, http.StatusTooManyRequests, http.StatusInternalServerError,
+		http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNABORTED) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE)
+}
+
+// requestThrottle spaces requests across all collectors to honour the
+// requests per second cap, and backs off while the server signals overload.
+type requestThrottle struct {
+	mu       sync.Mutex
+	next     time.Time
+	interval time.Duration
+	backoff  time.Duration
+}
+
+// wait blocks until the request may be sent. Waiting requests re-check after
+// sleeping, so a backoff also delays requests that were already waiting.
+func (t *requestThrottle) wait() {
+	for {
+		t.mu.Lock()
+		now := time.Now()
+		if !t.next.After(now) {
+			t.next = now.Add(max(t.interval, t.backoff))
+			t.mu.Unlock()
+			return
+		}
+		pause := t.next.Sub(now)
+		t.mu.Unlock()
+
+		time.Sleep(pause)
+	}
+}
+
+// slowDown doubles the pause between requests, or uses the server's
+// Retry-After if that is longer.
+func (t *requestThrottle) slowDown(retryAfter time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	backoff := min(max(t.backoff*2, minBackoff), maxBackoff)
+	backoff = max(backoff, retryAfter)
+	if backoff == t.backoff {
+		return
+	}
+
+	t.backoff = backoff
+	if next := time.Now().Add(backoff); next.After(t.next) {
+		t.next = next
+	}
+	utils.PrintWarning(fmt.Sprintf("Server is throttling requests, waiting %s between requests", backoff))
+}
+
+// recover halves the backoff after every successful response.
+func (t *requestThrottle) recover() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.backoff == 0 {
+		return
+	}
+	t.backoff /= 2
+	if t.backoff < minBackoff {
+		t.backoff = 0
+	}
+}
+
+// retryAfter parses the Retry-After header, given either in seconds or as an
+// HTTP date.
+func retryAfter(headers *http.Header) time.Duration {
+	if headers == nil {
+		return 0
+	}
+	value := headers.Get("Retry-After")
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return time.Until(date)
+	}
+	return 0
+}
@@ -0,0 +1,98 @@
+package engine
+
+import (
+	"encoding/gob"
+	"fmt"
+	"go-find-version/utils"
+	"os"
+	"path/filepath"
+)
+
+const (
+	indexFileName = "go-find-version.index"
+	indexVersion  = 2
+)
+
+type indexFile struct {
+	Version int
+	History *blobHistory
+}
+
+func indexPath(repository *CachedRepo) string {
+	return filepath.Join(repository.path, indexFileName)
+}
+
+// loadIndex returns the blob index of the repository. The index is read from
+// disk, brought up to date with the repository's current history and written
+// back if new commits had to be indexed.
+func loadIndex(repository *CachedRepo) (*blobHistory, error) {
+	if repository.index != nil {
+		return repository.index, nil
+	}
+
+	filename := indexPath(repository)
+	history, err := readIndex(filename)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			utils.PrintWarning("Rebuilding unreadable index: " + err.Error())
+		}
+		history = &blobHistory{}
+	}
+
+	utils.PrintInfo("Updating blob index")
+
+	changed, err := updateBlobHistory(repository.repo, history)
+	if err != nil {
+		return nil, err
+	}
+
+	if changed {
+		if err := writeIndex(filename, history); err != nil {
+			utils.PrintError(err, "Failed to save blob index")
+		}
+	}
+
+	utils.PrintInfo(fmt.Sprintf("Blob index covers %d commits and %d paths", len(history.Commits), len(history.Paths)))
+
+	repository.index = history
+	return history, nil
+}
+
+func readIndex(filename string) (*blobHistory, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var index indexFile
+	if err := gob.NewDecoder(file).Decode(&index); err != nil {
+		return nil, fmt.Errorf("failed to decode index: %v", err)
+	}
+	if index.Version != indexVersion || index.History == nil {
+		return nil, fmt.Errorf("index version %d is not supported", index.Version)
+	}
+	return index.History, nil
+}
+
+func writeIndex(filename string, history *blobHistory) error {
+	tmpName := filename + ".tmp"
+	file, err := os.Create(tmpName)
+	if err != nil {
+		return fmt.Errorf("failed to create index: %v", err)
+	}
+
+	err = gob.NewEncoder(file).Encode(indexFile{
+		Version: indexVersion,
+		History: history,
+	})
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpName)
+		return fmt.Errorf("failed to write index: %v", err)
+	}
+
+	return os.Rename(tmpName, filename)
+}
@@ -0,0 +1,257 @@
+package engine
+
+import (
+	"fmt"
+	"github.com/charmbracelet/bubbles/progress"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"slices"
+	"sort"
+	"time"
+)
+
+// blobInterval is a run of consecutive commits in linear history during
+// which a path held the same blob. Linear history keeps first-parent lines
+// together, so a run mostly follows a single branch. Start and End are
+// inclusive indices.
+type blobInterval struct {
+	Hash  plumbing.Hash
+	Start int
+	End   int
+}
+
+// commitRange is an inclusive span of indices into linear history.
+type commitRange struct {
+	Start int
+	End   int
+}
+
+type historyCommit struct {
+	Hash plumbing.Hash
+	When time.Time
+}
+
+// blobHistory holds the linear history of a repository together with the
+// blob intervals of every path that ever existed in it. Parents precede their
+// children, but commits are not ordered by time.
+type blobHistory struct {
+	Commits []historyCommit
+	Paths   map[string][]blobInterval
+}
+
+// updateBlobHistory walks the linear history and records, for every path, the
+// intervals during which each of its blobs was live. Commits already recorded
+// in history are kept as long as they are still reachable, so only new
+// commits are walked. Each commit is diffed against the one before it, which
+// linearHistory arranges to be its first parent except where a line starts,
+// so parallel branches don't reopen every path they differ in over and over.
+// Reports whether anything changed.
+func updateBlobHistory(repo *git.Repository, history *blobHistory) (bool, error) {
+	prefix, commits, err := linearHistory(repo, history.Commits)
+	if err != nil {
+		return false, err
+	}
+
+	if history.Paths == nil {
+		history.Paths = make(map[string][]blobInterval)
+	}
+
+	if prefix == len(history.Commits) && len(commits) == 0 {
+		return false, nil
+	}
+
+	var previousTree *object.Tree
+	if prefix > 0 {
+		commit, err := repo.CommitObject(history.Commits[prefix-1].Hash)
+		if err != nil {
+			return false, err
+		}
+		previousTree, err = commit.Tree()
+		if err != nil {
+			return false, err
+		}
+	}
+	history.truncate(prefix)
+
+	prgs := progress.New(
+		progress.WithWidth(40),
+		progress.WithoutPercentage(),
+		progress.WithScaledGradient("#FF7CCB", "#FDFF8C"),
+	)
+
+	m := &gitBasicModel{
+		progress: prgs,
+		title:    "Indexing history",
+		message:  "commits indexed",
+		total:    len(commits),
+		message2: "commits skipped",
+	}
+
+	p := tea.NewProgram(m)
+
+	go func() {
+		if _, err := p.Run(); err != nil {
+			fmt.Println("Error running UI:", err)
+		}
+	}()
+
+	for _, commit := range commits {
+		index := len(history.Commits)
+		history.Commits = append(history.Commits, historyCommit{
+			Hash: commit.Hash,
+			When: commit.Author.When,
+		})
+
+		// Commits whose tree can't be read are still recorded, holding the
+		// files of the commit before them, so the index stays a prefix of
+		// linear history and later runs don't walk them again
+		tree, err := commit.Tree()
+		if err != nil {
+			p.Send(countMsg2{})
+			continue
+		}
+		changes, err := object.DiffTree(previousTree, tree)
+		if err != nil {
+			p.Send(countMsg2{})
+			continue
+		}
+		previousTree = tree
+
+		for _, change := range changes {
+			if change.From.Name != "" {
+				history.close(change.From.Name, index-1)
+			}
+			if change.To.Name != "" {
+				history.Paths[change.To.Name] = append(history.Paths[change.To.Name], blobInterval{
+					Hash:  change.To.TreeEntry.Hash,
+					Start: index,
+					End:   -1,
+				})
+			}
+		}
+
+		p.Send(countMsg{})
+	}
+
+	for path := range history.Paths {
+		history.close(path, len(history.Commits)-1)
+	}
+
+	p.Quit()
+	return true, nil
+}
+
+// truncate drops every commit from index n on and reopens the intervals that
+// were still live at the last kept commit.
+func (h *blobHistory) truncate(n int) {
+	h.Commits = h.Commits[:n]
+	for path, intervals := range h.Paths {
+		kept := intervals[:0]
+		for _, interval := range intervals {
+			if interval.Start >= n {
+				continue
+			}
+			if interval.End >= n-1 {
+				interval.End = -1
+			}
+			kept = append(kept, interval)
+		}
+		if len(kept) == 0 {
+			delete(h.Paths, path)
+		} else {
+			h.Paths[path] = kept
+		}
+	}
+}
+
+// close ends the open interval of path, if any, at index end.
+func (h *blobHistory) close(path string, end int) {
+	intervals := h.Paths[path]
+	if len(intervals) == 0 || intervals[len(intervals)-1].End != -1 {
+		return
+	}
+	intervals[len(intervals)-1].End = end
+}
+
+// blobAt returns the blob a path held at the given commit index.
+func (h *blobHistory) blobAt(path string, index int) (plumbing.Hash, bool) {
+	intervals := h.Paths[path]
+	i := sort.Search(len(intervals), func(i int) bool {
+		return intervals[i].End >= index
+	})
+	if i < len(intervals) && intervals[i].Start <= index {
+		return intervals[i].Hash, true
+	}
+	return plumbing.ZeroHash, false
+}
+
+// rangesFor returns the commit ranges during which path held any of the blob
+// hashes.
+func (h *blobHistory) rangesFor(path string, hashes ...plumbing.Hash) []commitRange {
+	var ranges []commitRange
+	for _, interval := range h.Paths[path] {
+		if slices.Contains(hashes, interval.Hash) {
+			ranges = append(ranges, commitRange{Start: interval.Start, End: interval.End})
+		}
+	}
+	return ranges
+}
+
+// acceptedBlobs returns the blobs a webserver file is taken to match: every
+// blob it matched after normalization, or else its own hash.
+func acceptedBlobs(hash plumbing.Hash, normalized []plumbing.Hash) []plumbing.Hash {
+	if len(normalized) > 0 {
+		return normalized
+	}
+	return []plumbing.Hash{hash}
+}
+
+// consistentRanges intersects the validity ranges of every observed file.
+// Files whose hash never appeared at their path can't narrow the result and
+// are returned separately as unmatched. An empty result means no commit is
+// consistent with all matched files.
+func (h *blobHistory) consistentRanges(webserverHashes map[string]plumbing.Hash, normalizedBlobs map[string][]plumbing.Hash) ([]commitRange, []string) {
+	if len(h.Commits) == 0 {
+		return nil, nil
+	}
+
+	files := make([]string, 0, len(webserverHashes))
+	for file := range webserverHashes {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	result := []commitRange{{Start: 0, End: len(h.Commits) - 1}}
+	var unmatched []string
+	for _, file := range files {
+		ranges := h.rangesFor(file, acceptedBlobs(webserverHashes[file], normalizedBlobs[file])...)
+		if len(ranges) == 0 {
+			unmatched = append(unmatched, file)
+			continue
+		}
+		result = intersectRanges(result, ranges)
+	}
+
+	return result, unmatched
+}
+
+// intersectRanges intersects two sorted lists of non-overlapping ranges.
+func intersectRanges(a, b []commitRange) []commitRange {
+	var result []commitRange
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		start := max(a[i].Start, b[j].Start)
+		end := min(a[i].End, b[j].End)
+		if start <= end {
+			result = append(result, commitRange{Start: start, End: end})
+		}
+		if a[i].End < b[j].End {
+			i++
+		} else {
+			j++
+		}
+	}
+	return result
+}
@@ -0,0 +1,140 @@
+package engine
+
+import (
+	"fmt"
+	"github.com/go-git/go-git/v5/plumbing"
+	"reflect"
+	"testing"
+)
+
+// testHash returns a distinct, readable hash for test fixtures.
+func testHash(n int) plumbing.Hash {
+	return plumbing.NewHash(fmt.Sprintf("%040x", n))
+}
+
+func testHistory(commits int, paths map[string][]blobInterval) *blobHistory {
+	return &blobHistory{
+		Commits: make([]historyCommit, commits),
+		Paths:   paths,
+	}
+}
+
+func TestIntersectRanges(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []commitRange
+		want []commitRange
+	}{
+		{"disjoint", []commitRange{{0, 2}}, []commitRange{{3, 5}}, nil},
+		{"overlap", []commitRange{{0, 4}}, []commitRange{{2, 6}}, []commitRange{{2, 4}}},
+		{"contained", []commitRange{{0, 9}}, []commitRange{{2, 3}, {5, 7}}, []commitRange{{2, 3}, {5, 7}}},
+		{"touching", []commitRange{{0, 2}}, []commitRange{{2, 5}}, []commitRange{{2, 2}}},
+		{"interleaved", []commitRange{{0, 1}, {4, 8}}, []commitRange{{1, 5}, {7, 9}}, []commitRange{{1, 1}, {4, 5}, {7, 8}}},
+		{"empty", nil, []commitRange{{0, 3}}, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := intersectRanges(test.a, test.b); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("intersectRanges(%v, %v) = %v, want %v", test.a, test.b, got, test.want)
+			}
+		})
+	}
+}
+
+func TestConsistentRanges(t *testing.T) {
+	history := testHistory(6, map[string][]blobInterval{
+		"a": {{testHash(1), 0, 2}, {testHash(2), 3, 5}},
+		"b": {{testHash(3), 0, 0}, {testHash(4), 1, 4}},
+	})
+
+	tests := []struct {
+		name          string
+		hashes        map[string]plumbing.Hash
+		normalized    map[string][]plumbing.Hash
+		wantRanges    []commitRange
+		wantUnmatched []string
+	}{
+		{
+			name:       "single file",
+			hashes:     map[string]plumbing.Hash{"a": testHash(2)},
+			wantRanges: []commitRange{{3, 5}},
+		},
+		{
+			name:       "intersection",
+			hashes:     map[string]plumbing.Hash{"a": testHash(1), "b": testHash(4)},
+			wantRanges: []commitRange{{1, 2}},
+		},
+		{
+			name:   "contradiction",
+			hashes: map[string]plumbing.Hash{"a": testHash(2), "b": testHash(3)},
+		},
+		{
+			name:          "unknown hash",
+			hashes:        map[string]plumbing.Hash{"a": testHash(1), "c": testHash(5), "b": testHash(9)},
+			wantRanges:    []commitRange{{0, 2}},
+			wantUnmatched: []string{"b", "c"},
+		},
+		{
+			name:       "normalized blobs",
+			hashes:     map[string]plumbing.Hash{"a": testHash(1), "b": testHash(4)},
+			normalized: map[string][]plumbing.Hash{"a": {testHash(1), testHash(2)}},
+			wantRanges: []commitRange{{1, 2}, {3, 4}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ranges, unmatched := history.consistentRanges(test.hashes, test.normalized)
+			if !reflect.DeepEqual(ranges, test.wantRanges) {
+				t.Errorf("ranges = %v, want %v", ranges, test.wantRanges)
+			}
+			if !reflect.DeepEqual(unmatched, test.wantUnmatched) {
+				t.Errorf("unmatched = %v, want %v", unmatched, test.wantUnmatched)
+			}
+		})
+	}
+}
+
+func TestBlobAt(t *testing.T) {
+	history := testHistory(8, map[string][]blobInterval{
+		"a": {{testHash(1), 1, 2}, {testHash(2), 5, 7}},
+	})
+
+	tests := []struct {
+		index  int
+		want   plumbing.Hash
+		exists bool
+	}{
+		{0, plumbing.ZeroHash, false},
+		{1, testHash(1), true},
+		{2, testHash(1), true},
+		{3, plumbing.ZeroHash, false},
+		{5, testHash(2), true},
+		{7, testHash(2), true},
+	}
+	for _, test := range tests {
+		got, exists := history.blobAt("a", test.index)
+		if got != test.want || exists != test.exists {
+			t.Errorf("blobAt(a, %d) = %v, %v, want %v, %v", test.index, got, exists, test.want, test.exists)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	history := testHistory(6, map[string][]blobInterval{
+		"a": {{testHash(1), 0, 1}, {testHash(2), 2, 5}},
+		"b": {{testHash(3), 0, 2}, {testHash(4), 3, 5}},
+		"c": {{testHash(5), 4, 5}},
+	})
+	history.truncate(3)
+
+	want := map[string][]blobInterval{
+		"a": {{testHash(1), 0, 1}, {testHash(2), 2, -1}},
+		"b": {{testHash(3), 0, -1}},
+	}
+	if len(history.Commits) != 3 {
+		t.Errorf("kept %d commits, want 3", len(history.Commits))
+	}
+	if !reflect.DeepEqual(history.Paths, want) {
+		t.Errorf("paths = %v, want %v", history.Paths, want)
+	}
+}
@@ -0,0 +1,161 @@
+package engine
+
+import (
+	"fmt"
+	"github.com/go-git/go-git/v5/plumbing"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// linkProvider builds web UI links for commits of a repository. Providers
+// return an empty string when they have no link to offer.
+type linkProvider interface {
+	CommitLink(hash plumbing.Hash) string
+	CompareLink(from, to plumbing.Hash) string
+}
+
+var forgeNames = []string{"github", "gitlab", "gitea", "bitbucket", "cgit", "none"}
+
+type githubLinks struct{ base string }
+
+func (l githubLinks) CommitLink(hash plumbing.Hash) string {
+	return fmt.Sprintf("%s/commit/%s", l.base, hash)
+}
+
+func (l githubLinks) CompareLink(from, to plumbing.Hash) string {
+	return fmt.Sprintf("%s/compare/%s..%s", l.base, from, to)
+}
+
+type gitlabLinks struct{ base string }
+
+func (l gitlabLinks) CommitLink(hash plumbing.Hash) string {
+	return fmt.Sprintf("%s/-/commit/%s", l.base, hash)
+}
+
+func (l gitlabLinks) CompareLink(from, to plumbing.Hash) string {
+	return fmt.Sprintf("%s/-/compare/%s...%s", l.base, from, to)
+}
+
+// giteaLinks covers Gitea and its fork Forgejo
+type giteaLinks struct{ base string }
+
+func (l giteaLinks) CommitLink(hash plumbing.Hash) string {
+	return fmt.Sprintf("%s/commit/%s", l.base, hash)
+}
+
+func (l giteaLinks) CompareLink(from, to plumbing.Hash) string {
+	return fmt.Sprintf("%s/compare/%s...%s", l.base, from, to)
+}
+
+type bitbucketLinks struct{ base string }
+
+func (l bitbucketLinks) CommitLink(hash plumbing.Hash) string {
+	return fmt.Sprintf("%s/commits/%s", l.base, hash)
+}
+
+func (l bitbucketLinks) CompareLink(from, to plumbing.Hash) string {
+	// Bitbucket lists the newer revision first
+	return fmt.Sprintf("%s/branches/compare/%s%%0D%s", l.base, to, from)
+}
+
+type cgitLinks struct{ base string }
+
+func (l cgitLinks) CommitLink(hash plumbing.Hash) string {
+	return fmt.Sprintf("%s/commit/?id=%s", l.base, hash)
+}
+
+func (l cgitLinks) CompareLink(from, to plumbing.Hash) string {
+	return fmt.Sprintf("%s/diff/?id=%s&id2=%s", l.base, to, from)
+}
+
+type noLinks struct{}
+
+func (noLinks) CommitLink(plumbing.Hash) string {
+	return ""
+}
+
+func (noLinks) CompareLink(plumbing.Hash, plumbing.Hash) string {
+	return ""
+}
+
+// newLinkProvider picks the link style for a repository. An empty forge is
+// detected from the host of baseUrl if given, else from the remote host;
+// baseUrl overrides the web UI address, which is needed for local clones and
+// for mirrors served from another host.
+func newLinkProvider(locator *RepoLocator, forge, baseUrl string) (linkProvider, error) {
+	base := strings.TrimSuffix(baseUrl, "/")
+	if base == "" {
+		if locator.IsLocal() {
+			return noLinks{}, nil
+		}
+		base = webBaseUrl(locator)
+	}
+
+	switch {
+	case forge != "":
+	case baseUrl != "":
+		// The web UI given is what links point to, so its host tells the forge
+		u, err := url.Parse(base)
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("invalid forge url %q", baseUrl)
+		}
+		forge = detectForge(u.Hostname())
+		if forge == "none" {
+			return nil, fmt.Errorf("can't tell the forge of %s, pass --forge with one of %s", u.Host, strings.Join(forgeNames, ", "))
+		}
+	case locator.IsLocal():
+		forge = "none"
+	default:
+		forge = detectForge(locator.Host)
+	}
+
+	switch strings.ToLower(forge) {
+	case "github":
+		return githubLinks{base}, nil
+	case "gitlab":
+		return gitlabLinks{base}, nil
+	case "gitea", "forgejo":
+		return giteaLinks{base}, nil
+	case "bitbucket":
+		return bitbucketLinks{base}, nil
+	case "cgit":
+		return cgitLinks{base}, nil
+	case "none":
+		return noLinks{}, nil
+	}
+	return nil, fmt.Errorf("unknown forge %q, expected one of %s", forge, strings.Join(forgeNames, ", "))
+}
+
+// webBaseUrl guesses the web UI address of a remote repository. SSH and git
+// remotes are assumed to be browsable over HTTPS on the same host.
+func webBaseUrl(locator *RepoLocator) string {
+	scheme, host := "https", locator.Host
+	switch locator.Kind {
+	case locatorHTTP:
+		scheme = "http"
+	case locatorSSH, locatorGit:
+		if hostname, _, err := net.SplitHostPort(host); err == nil {
+			host = hostname
+		}
+	}
+	return fmt.Sprintf("%s://%s/%s", scheme, host, locator.FullName())
+}
+
+// detectForge guesses the forge from well known host names, or returns
+// "none".
+func detectForge(host string) string {
+	switch {
+	case strings.Contains(host, "github"):
+		return "github"
+	case strings.Contains(host, "gitlab"):
+		return "gitlab"
+	case strings.Contains(host, "bitbucket"):
+		return "bitbucket"
+	case strings.Contains(host, "gitea"), strings.Contains(host, "forgejo"), strings.Contains(host, "codeberg"):
+		return "gitea"
+	case strings.Contains(host, "cgit"), host == "git.kernel.org":
+		return "cgit"
+	}
+	return "none"
+}
@@ -0,0 +1,163 @@
+package engine
+
+import (
+	"fmt"
+	"github.com/go-git/go-git/v5/plumbing"
+	"go-find-version/utils"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// checkLocalHashes hashes the files of a deployment directory with the git
+// blob algorithm, replacing checkFileHashes for audits with filesystem
+// access. Files are mapped to local paths with the rewrite rules, like they
+// are mapped to URLs, and files that don't exist locally are left out.
+func checkLocalHashes(files []string, dir string, options checkOptions) (*fileCheckResult, error) {
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	}
+
+	utils.PrintInfo("Hashing files in " + dir)
+
+	result := newFileCheckResult()
+	for _, file := range files {
+		localPath, ok := options.Rules.WebPath(file)
+		if !ok {
+			continue
+		}
+		fullPath := filepath.Join(dir, filepath.FromSlash(localPath))
+
+		// Symlinks are stored as their target in git and would never match
+		info, err := os.Lstat(fullPath)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		body, err := os.ReadFile(fullPath)
+		if err != nil {
+			utils.PrintError(err, "Failed to read "+fullPath)
+			continue
+		}
+		result.add(file, body, hashBlob(body), options)
+	}
+
+	utils.PrintInfo(fmt.Sprintf("Found %d of %d files in the directory", len(result.Hashes), len(files)))
+	return result, nil
+}
+
+// localDivergence lists how a deployment directory differs from the commit it
+// was deployed from. Paths are repository paths, except for added files that
+// no repository path maps to.
+type localDivergence struct {
+	Modified []string `json:"modified"`
+	Added    []string `json:"added"`
+	Missing  []string `json:"missing"`
+}
+
+func findLocalDivergence(repository *CachedRepo, source plumbing.Hash, dir string, files []string, checked *fileCheckResult, rules rewriteRules, filter *fileFilter) (*localDivergence, error) {
+	commit, err := repository.repo.CommitObject(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commit %s: %v", source, err)
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tree of %s: %v", source, err)
+	}
+
+	divergence := &localDivergence{
+		Modified: []string{},
+		Added:    []string{},
+		Missing:  []string{},
+	}
+
+	for file, hash := range checked.Hashes {
+		entry, err := tree.File(file)
+		if err != nil {
+			divergence.Added = append(divergence.Added, file)
+		} else if entry.Hash != hash {
+			divergence.Modified = append(divergence.Modified, file)
+		}
+	}
+
+	for _, file := range files {
+		if _, ok := rules.WebPath(file); !ok {
+			continue
+		}
+		if _, found := checked.Hashes[file]; found {
+			continue
+		}
+		if _, err := tree.File(file); err == nil {
+			divergence.Missing = append(divergence.Missing, file)
+		}
+	}
+
+	// Every path the repository ever held is known, including the ones left
+	// out of the check by --servable-only
+	history, err := loadIndex(repository)
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]bool, len(history.Paths))
+	for file := range history.Paths {
+		if localPath, ok := rules.WebPath(file); ok {
+			known[strings.TrimPrefix(localPath, "/")] = true
+		}
+	}
+
+	// Files no repository path ever mapped to, e.g. uploads and local config
+	err = filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() && entry.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if !known[rel] && filter.Match(rel) {
+			divergence.Added = append(divergence.Added, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk %s: %v", dir, err)
+	}
+
+	sort.Strings(divergence.Modified)
+	sort.Strings(divergence.Added)
+	sort.Strings(divergence.Missing)
+	return divergence, nil
+}
+
+func displayLocalDivergence(divergence *localDivergence) {
+	utils.PrintInfo(fmt.Sprintf("Local changes: %d modified, %d added, %d missing",
+		len(divergence.Modified), len(divergence.Added), len(divergence.Missing)))
+
+	for _, group := range []struct {
+		name  string
+		files []string
+	}{
+		{"Modified", divergence.Modified},
+		{"Added", divergence.Added},
+		{"Missing", divergence.Missing},
+	} {
+		if len(group.files) == 0 {
+			continue
+		}
+		fmt.Printf("  %s:\n", group.name)
+		for _, file := range group.files[:min(10, len(group.files))] {
+			fmt.Printf("    %s\n", file)
+		}
+		if len(group.files) > 10 {
+			fmt.Printf("    ... and %d more\n", len(group.files)-10)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package engine
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	locatorHTTPS = "https"
+	locatorHTTP  = "http"
+	locatorSSH   = "ssh"
+	locatorGit   = "git"
+	locatorFile  = "file"
+	locatorLocal = "local"
+)
+
+// RepoLocator describes where a repository lives, independent of the syntax
+// used to name it (HTTPS, SSH, scp-like, file:// or a plain path).
+type RepoLocator struct {
+	URL       string
+	Kind      string
+	Host      string
+	Namespace []string
+	Name      string
+}
+
+var scpLikePattern = regexp.MustCompile(`^(?:[^@/]+@)?([^:/]+):(.+)$`)
+var unsafeSegmentPattern = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+func parseRepoLocator(uri string) (*RepoLocator, error) {
+	uri = strings.TrimSpace(uri)
+	if uri == "" {
+		return nil, fmt.Errorf("empty repository location")
+	}
+
+	if strings.Contains(uri, "://") {
+		u, err := url.Parse(uri)
+		if err != nil {
+			return nil, fmt.Errorf("invalid repository url: %v", err)
+		}
+
+		switch u.Scheme {
+		case locatorFile:
+			return newLocalLocator(locatorFile, u.Path)
+		case locatorHTTPS, locatorHTTP, locatorSSH, locatorGit:
+			return newRemoteLocator(uri, u.Scheme, u.Host, u.Path)
+		}
+		return nil, fmt.Errorf("unsupported repository scheme %q", u.Scheme)
+	}
+
+	// git@host:owner/repo.git, but not Windows drive letters like C:\repo
+	if match := scpLikePattern.FindStringSubmatch(uri); match != nil && len(match[1]) > 1 {
+		return newRemoteLocator(uri, locatorSSH, match[1], match[2])
+	}
+
+	return newLocalLocator(locatorLocal, uri)
+}
+
+func newRemoteLocator(uri, kind, host, repoPath string) (*RepoLocator, error) {
+	segments := splitRepoPath(repoPath)
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("repository url %q has no repository path", uri)
+	}
+
+	return &RepoLocator{
+		URL:       uri,
+		Kind:      kind,
+		Host:      strings.ToLower(host),
+		Namespace: segments[:len(segments)-1],
+		Name:      segments[len(segments)-1],
+	}, nil
+}
+
+func newLocalLocator(kind, localPath string) (*RepoLocator, error) {
+	absPath, err := filepath.Abs(localPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Point at the work tree rather than its .git directory
+	if filepath.Base(absPath) == ".git" {
+		absPath = filepath.Dir(absPath)
+	}
+
+	return &RepoLocator{
+		URL:  absPath,
+		Kind: kind,
+		Name: strings.TrimSuffix(filepath.Base(absPath), ".git"),
+	}, nil
+}
+
+// splitRepoPath splits "/group/sub/repo.git/" into its path segments.
+func splitRepoPath(repoPath string) []string {
+	var segments []string
+	for _, segment := range strings.Split(repoPath, "/") {
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	if len(segments) > 0 {
+		segments[len(segments)-1] = strings.TrimSuffix(segments[len(segments)-1], ".git")
+	}
+	return segments
+}
+
+func (l *RepoLocator) IsLocal() bool {
+	return l.Kind == locatorLocal || l.Kind == locatorFile
+}
+
+// FullName is the namespaced repository name, e.g. "group/sub/repo".
+func (l *RepoLocator) FullName() string {
+	return path.Join(append(append([]string{}, l.Namespace...), l.Name)...)
+}
+
+// DisplayName identifies the repository in reports.
+func (l *RepoLocator) DisplayName() string {
+	if l.IsLocal() {
+		return l.URL
+	}
+	return l.Host + "/" + l.FullName()
+}
+
+// CacheKey is the stable, filesystem-safe path of the cached clone relative
+// to the data directory. GitHub repositories keep the historical
+// "<owner>/<repo>" layout so existing caches stay valid.
+func (l *RepoLocator) CacheKey() string {
+	var segments []string
+
+	switch {
+	case l.IsLocal():
+		sum := sha1.Sum([]byte(l.URL))
+		segments = []string{"local", l.Name + "-" + hex.EncodeToString(sum[:])[:12]}
+	case l.Host == "github.com":
+		segments = append(append(segments, l.Namespace...), l.Name)
+	default:
+		segments = append(append([]string{l.Host}, l.Namespace...), l.Name)
+	}
+
+	for i, segment := range segments {
+		segment = unsafeSegmentPattern.ReplaceAllString(segment, "_")
+		if strings.Trim(segment, ".") == "" {
+			segment = "_"
+		}
+		segments[i] = segment
+	}
+	return filepath.Join(segments...)
+}
@@ -0,0 +1,118 @@
+package engine
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseRepoLocator(t *testing.T) {
+	tests := []struct {
+		uri       string
+		kind      string
+		host      string
+		namespace []string
+		name      string
+		display   string
+		cacheKey  string
+	}{
+		{
+			uri:       "https://github.com/drupal/drupal.git",
+			kind:      locatorHTTPS,
+			host:      "github.com",
+			namespace: []string{"drupal"},
+			name:      "drupal",
+			display:   "github.com/drupal/drupal",
+			cacheKey:  "drupal/drupal",
+		},
+		{
+			uri:       "http://GitLab.Example.org/group/sub/repo/",
+			kind:      locatorHTTP,
+			host:      "gitlab.example.org",
+			namespace: []string{"group", "sub"},
+			name:      "repo",
+			display:   "gitlab.example.org/group/sub/repo",
+			cacheKey:  "gitlab.example.org/group/sub/repo",
+		},
+		{
+			uri:       "git@gitlab.com:group/sub/repo.git",
+			kind:      locatorSSH,
+			host:      "gitlab.com",
+			namespace: []string{"group", "sub"},
+			name:      "repo",
+			display:   "gitlab.com/group/sub/repo",
+			cacheKey:  "gitlab.com/group/sub/repo",
+		},
+		{
+			uri:       "ssh://git@git.example.org:2222/team/app",
+			kind:      locatorSSH,
+			host:      "git.example.org:2222",
+			namespace: []string{"team"},
+			name:      "app",
+			display:   "git.example.org:2222/team/app",
+			cacheKey:  "git.example.org_2222/team/app",
+		},
+		{
+			uri:       "git://git.kernel.org/pub/scm/git/git.git",
+			kind:      locatorGit,
+			host:      "git.kernel.org",
+			namespace: []string{"pub", "scm", "git"},
+			name:      "git",
+			display:   "git.kernel.org/pub/scm/git/git",
+			cacheKey:  "git.kernel.org/pub/scm/git/git",
+		},
+		{
+			uri:     "file:///srv/repos/app.git",
+			kind:    locatorFile,
+			name:    "app",
+			display: "/srv/repos/app.git",
+		},
+		{
+			uri:     "/srv/work/.git",
+			kind:    locatorLocal,
+			name:    "work",
+			display: "/srv/work",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.uri, func(t *testing.T) {
+			locator, err := parseRepoLocator(test.uri)
+			if err != nil {
+				t.Fatalf("parseRepoLocator(%q) failed: %v", test.uri, err)
+			}
+			if locator.Kind != test.kind || locator.Host != test.host || locator.Name != test.name {
+				t.Errorf("got kind %q, host %q, name %q, want %q, %q, %q", locator.Kind, locator.Host, locator.Name, test.kind, test.host, test.name)
+			}
+			if len(locator.Namespace) > 0 || len(test.namespace) > 0 {
+				if !reflect.DeepEqual(locator.Namespace, test.namespace) {
+					t.Errorf("namespace = %v, want %v", locator.Namespace, test.namespace)
+				}
+			}
+			if display := locator.DisplayName(); display != test.display {
+				t.Errorf("DisplayName() = %q, want %q", display, test.display)
+			}
+			if test.cacheKey != "" && locator.CacheKey() != filepath.FromSlash(test.cacheKey) {
+				t.Errorf("CacheKey() = %q, want %q", locator.CacheKey(), test.cacheKey)
+			}
+		})
+	}
+}
+
+func TestParseRepoLocatorErrors(t *testing.T) {
+	for _, uri := range []string{"", "  ", "ftp://example.org/repo", "https://github.com/", "git@github.com:/"} {
+		if locator, err := parseRepoLocator(uri); err == nil {
+			t.Errorf("parseRepoLocator(%q) = %+v, want an error", uri, locator)
+		}
+	}
+}
+
+func TestLocalCacheKey(t *testing.T) {
+	a, _ := parseRepoLocator("/srv/one/app")
+	b, _ := parseRepoLocator("/srv/two/app")
+	if a.CacheKey() == b.CacheKey() {
+		t.Errorf("repositories at different paths share the cache key %q", a.CacheKey())
+	}
+	if filepath.Dir(a.CacheKey()) != "local" {
+		t.Errorf("CacheKey() = %q, want it below local/", a.CacheKey())
+	}
+}
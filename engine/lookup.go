@@ -0,0 +1,220 @@
+package engine
+
+import (
+	"fmt"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/gocolly/colly"
+	"go-find-version/utils"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+type LookupReport struct {
+	Path        string        `json:"path"`
+	Source      string        `json:"source"`
+	Hash        string        `json:"hash"`
+	Ranges      []LookupRange `json:"ranges"`
+	OtherPaths  []string      `json:"other_paths,omitempty"`
+	CommitCount int           `json:"commit_count"`
+}
+
+type LookupRange struct {
+	First string    `json:"first"`
+	Last  string    `json:"last"`
+	Count int       `json:"count"`
+	From  time.Time `json:"from"`
+	Until time.Time `json:"until"`
+	Tags  []string  `json:"tags"`
+
+	Commits []string `json:"commits"`
+}
+
+// runLookup hashes a single file and lists every commit and tag in which the
+// given repository path had exactly that content.
+func runLookup(args utils.Args) {
+	data, err := readLookupSource(args.LookupSource)
+	if err != nil {
+		utils.PrintError(err, "Failed to read "+args.LookupSource)
+		return
+	}
+
+	hash := hashBlob(data)
+	path := strings.TrimPrefix(args.LookupPath, "/")
+	utils.PrintInfo(fmt.Sprintf("Blob hash of %s is %s", args.LookupSource, hash))
+
+	repository, err := cloneRepo(args.GitUrl)
+	if err != nil {
+		utils.PrintError(err, "Failed to clone repository")
+		return
+	}
+
+	history, err := loadIndex(repository)
+	if err != nil {
+		utils.PrintError(err, "Failed to index repository")
+		return
+	}
+
+	tagCommits, err := loadTagCommits(repository.repo)
+	if err != nil {
+		utils.PrintError(err, "Failed to load tags")
+	}
+
+	report := &LookupReport{
+		Path:   path,
+		Source: args.LookupSource,
+		Hash:   hash.String(),
+		Ranges: []LookupRange{},
+	}
+
+	for _, r := range history.rangesFor(path, hash) {
+		lookupRange := LookupRange{
+			First: history.Commits[r.Start].Hash.String(),
+			Last:  history.Commits[r.End].Hash.String(),
+			Count: r.End - r.Start + 1,
+			From:  history.Commits[r.Start].When,
+			Until: history.Commits[r.End].When,
+			Tags:  []string{},
+		}
+		for index := r.Start; index <= r.End; index++ {
+			lookupRange.Commits = append(lookupRange.Commits, history.Commits[index].Hash.String())
+			lookupRange.Tags = append(lookupRange.Tags, tagCommits[history.Commits[index].Hash]...)
+		}
+		report.Ranges = append(report.Ranges, lookupRange)
+		report.CommitCount += lookupRange.Count
+	}
+
+	if len(report.Ranges) == 0 {
+		report.OtherPaths = findBlobPaths(history, hash)
+	}
+
+	displayLookup(report)
+
+	if args.Output != "" {
+		if err := saveReport(report, args.Output); err != nil {
+			utils.PrintError(err, "Failed to save report")
+		} else {
+			utils.PrintInfo("Report saved to " + args.Output)
+		}
+	}
+}
+
+// readLookupSource reads a local file, or downloads it if source is a URL.
+func readLookupSource(source string) ([]byte, error) {
+	u, err := url.Parse(source)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return os.ReadFile(source)
+	}
+
+	var (
+		body     []byte
+		fetched  bool
+		fetchErr error
+	)
+
+	c := newCollector(false)
+	c.OnResponse(func(r *colly.Response) {
+		// Hash the content the same way checkFileHashes does
+		if _, err := decodeResponse(r); err != nil {
+			fetchErr = err
+			return
+		}
+		body = r.Body
+		fetched = true
+	})
+	c.OnError(func(r *colly.Response, err error) {
+		// Retries run synchronously and report their own outcome
+		if shouldRetry(r, err) && retryRequest(r) == nil {
+			return
+		}
+		fetchErr = err
+	})
+
+	// Visit returns the error of the first attempt even if a retry succeeded
+	err = c.Visit(source)
+	if fetched {
+		return body, nil
+	}
+	if fetchErr != nil {
+		return nil, fetchErr
+	}
+	return nil, err
+}
+
+// findBlobPaths lists every path that held the blob at some point.
+func findBlobPaths(history *blobHistory, hash plumbing.Hash) []string {
+	var paths []string
+	for path, intervals := range history.Paths {
+		for _, interval := range intervals {
+			if interval.Hash == hash {
+				paths = append(paths, path)
+				break
+			}
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func displayLookup(report *LookupReport) {
+	headerStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color("#FF7CCB")).
+		Underline(true).
+		MarginBottom(1)
+
+	commitHashStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("#7FFFD4")).
+		Bold(true)
+
+	dateStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("#D3D3D3")).
+		Italic(true)
+
+	countStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("#FF69B4")).
+		Bold(true)
+
+	tagStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("#98FB98")).
+		Bold(true)
+
+	var output strings.Builder
+
+	output.WriteString(headerStyle.Render("🔎 Blob Lookup Results\n"))
+	output.WriteString(fmt.Sprintf("  %s @ %s\n\n", report.Path, commitHashStyle.Render(report.Hash)))
+
+	if len(report.Ranges) == 0 {
+		output.WriteString("  No commit ever had this content at this path\n")
+		if len(report.OtherPaths) > 0 {
+			output.WriteString("  The same content exists at:\n")
+			for _, path := range report.OtherPaths {
+				output.WriteString(fmt.Sprintf("    %s\n", path))
+			}
+		}
+		fmt.Println(output.String())
+		return
+	}
+
+	output.WriteString(fmt.Sprintf("  Found in %s commits across %s ranges\n",
+		countStyle.Render(fmt.Sprintf("%d", report.CommitCount)),
+		countStyle.Render(fmt.Sprintf("%d", len(report.Ranges))),
+	))
+
+	for _, r := range report.Ranges {
+		output.WriteString(fmt.Sprintf("\n  %s..%s (%d commits)\n",
+			commitHashStyle.Render(r.First[:7]),
+			commitHashStyle.Render(r.Last[:7]),
+			r.Count,
+		))
+		output.WriteString(fmt.Sprintf("     📅 %s - %s\n", dateStyle.Render(r.From.Format(time.RFC1123)), dateStyle.Render(r.Until.Format(time.RFC1123))))
+		if len(r.Tags) > 0 {
+			output.WriteString(fmt.Sprintf("     🏷️  %s\n", tagStyle.Render(strings.Join(r.Tags, ", "))))
+		}
+	}
+
+	fmt.Println(output.String())
+}
@@ -0,0 +1,178 @@
+package engine
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/go-git/go-git/v5/plumbing"
+	"go-find-version/utils"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// normalization undoes one kind of transformation servers apply to text
+// files in transit.
+type normalization struct {
+	Name  string
+	apply func([]byte) []byte
+}
+
+var normalizations = map[string]normalization{
+	"eol": {"eol", func(data []byte) []byte {
+		data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+		return bytes.ReplaceAll(data, []byte("\r"), []byte("\n"))
+	}},
+	"bom": {"bom", func(data []byte) []byte {
+		return bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	}},
+	"trailing-newline": {"trailing-newline", func(data []byte) []byte {
+		return bytes.TrimRight(data, "\r\n")
+	}},
+	"whitespace": {"whitespace", func(data []byte) []byte {
+		lines := bytes.Split(data, []byte("\n"))
+		for i, line := range lines {
+			lines[i] = bytes.TrimRight(line, " \t\r")
+		}
+		return bytes.Join(lines, []byte("\n"))
+	}},
+}
+
+// normalizationProfiles bundle the individual normalizations.
+var normalizationProfiles = map[string][]string{
+	"text":  {"bom", "eol", "trailing-newline"},
+	"loose": {"bom", "eol", "whitespace", "trailing-newline"},
+}
+
+type normalizer []normalization
+
+func parseNormalizer(names []string) (normalizer, error) {
+	var result normalizer
+	seen := make(map[string]bool)
+
+	var add func(name string) error
+	add = func(name string) error {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if steps, ok := normalizationProfiles[name]; ok {
+			for _, step := range steps {
+				if err := add(step); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		step, ok := normalizations[name]
+		if !ok {
+			return fmt.Errorf("unknown normalization %q, expected one of %s", name, strings.Join(normalizationNames(), ", "))
+		}
+		if !seen[name] {
+			seen[name] = true
+			result = append(result, step)
+		}
+		return nil
+	}
+
+	for _, name := range names {
+		for _, part := range strings.Split(name, ",") {
+			if err := add(part); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return result, nil
+}
+
+func normalizationNames() []string {
+	var names []string
+	for name := range normalizationProfiles {
+		names = append(names, name)
+	}
+	for name := range normalizations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Apply normalizes text content. Binary content, detected like git does by a
+// NUL byte near the start, is returned unchanged.
+func (n normalizer) Apply(data []byte) []byte {
+	head := data
+	if len(head) > 8000 {
+		head = head[:8000]
+	}
+	if bytes.IndexByte(head, 0) >= 0 {
+		return data
+	}
+
+	for _, step := range n {
+		data = step.apply(data)
+	}
+	return data
+}
+
+func (n normalizer) Names() []string {
+	names := make([]string, 0, len(n))
+	for _, step := range n {
+		names = append(names, step.Name)
+	}
+	return names
+}
+
+// matchNormalized retries files whose exact hash is unknown at their path by
+// comparing the normalized response with every normalized blob the path ever
+// held. Every matching blob is kept in NormalizedBlobs, as blobs that only
+// differ in what is normalized away are equally likely, and matching files
+// are mapped to the first of them and returned.
+func matchNormalized(repository *CachedRepo, checked *fileCheckResult, normalize normalizer) ([]string, error) {
+	if len(normalize) == 0 {
+		return nil, nil
+	}
+
+	history, err := loadIndex(repository)
+	if err != nil {
+		return nil, err
+	}
+
+	normalizedBlobs := make(map[plumbing.Hash]plumbing.Hash)
+	var matched []string
+
+	for file, hash := range checked.Hashes {
+		if len(history.rangesFor(file, hash)) > 0 {
+			continue
+		}
+		target, ok := checked.NormalizedHashes[file]
+		if !ok {
+			continue
+		}
+
+		for _, interval := range history.Paths[file] {
+			normalized, ok := normalizedBlobs[interval.Hash]
+			if !ok {
+				normalized, err = normalizedBlobHash(repository, interval.Hash, normalize)
+				if err != nil {
+					utils.PrintWarning(fmt.Sprintf("Failed to read blob %s of %s: %v", interval.Hash, file, err))
+					continue
+				}
+				normalizedBlobs[interval.Hash] = normalized
+			}
+			if normalized == target && !slices.Contains(checked.NormalizedBlobs[file], interval.Hash) {
+				checked.NormalizedBlobs[file] = append(checked.NormalizedBlobs[file], interval.Hash)
+			}
+		}
+		if blobs := checked.NormalizedBlobs[file]; len(blobs) > 0 {
+			checked.Hashes[file] = blobs[0]
+			matched = append(matched, file)
+		}
+	}
+
+	sort.Strings(matched)
+	return matched, nil
+}
+
+func normalizedBlobHash(repository *CachedRepo, hash plumbing.Hash, normalize normalizer) (plumbing.Hash, error) {
+	data, err := readBlob(repository, hash)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	return hashBlob(normalize.Apply(data)), nil
+}
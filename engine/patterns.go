@@ -0,0 +1,102 @@
+package engine
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+	"os"
+	"strings"
+)
+
+// defaultExcludePatterns skip sources that are compiled before deployment and
+// never served verbatim. They apply only when no exclude patterns are given.
+var defaultExcludePatterns = []string{
+	"*.vue",
+	"*.ts",
+}
+
+// fileFilter selects repository paths with gitignore-style patterns. A path is
+// kept if it matches the include patterns (or there are none) and does not
+// match the exclude patterns.
+type fileFilter struct {
+	include gitignore.Matcher
+	exclude gitignore.Matcher
+}
+
+func newFileFilter(include, exclude []string, includeFile, excludeFile string) (*fileFilter, error) {
+	if includeFile != "" {
+		filePatterns, err := readPatternFile(includeFile)
+		if err != nil {
+			return nil, err
+		}
+		include = append(include, filePatterns...)
+	}
+
+	if excludeFile != "" {
+		filePatterns, err := readPatternFile(excludeFile)
+		if err != nil {
+			return nil, err
+		}
+		exclude = append(exclude, filePatterns...)
+	}
+
+	if len(exclude) == 0 {
+		exclude = defaultExcludePatterns
+	}
+
+	filter := &fileFilter{exclude: newPatternMatcher(exclude)}
+	if len(include) > 0 {
+		filter.include = newPatternMatcher(include)
+	}
+	return filter, nil
+}
+
+func newPatternMatcher(patterns []string) gitignore.Matcher {
+	var ps []gitignore.Pattern
+	for _, p := range patterns {
+		ps = append(ps, gitignore.ParsePattern(p, nil))
+	}
+	return gitignore.NewMatcher(ps)
+}
+
+// readPatternFile reads one pattern per line, skipping blank lines and
+// comments, like a .gitignore file.
+func readPatternFile(filename string) ([]string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open pattern file: %v", err)
+	}
+	defer file.Close()
+
+	var patterns []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		patterns = append(patterns, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read pattern file: %v", err)
+	}
+	return patterns, nil
+}
+
+func (f *fileFilter) Match(file string) bool {
+	pathSegments := strings.Split(strings.TrimPrefix(file, "/"), "/")
+	if f.include != nil && !f.include.Match(pathSegments, false) {
+		return false
+	}
+	return !f.exclude.Match(pathSegments, false)
+}
+
+func (f *fileFilter) Apply(files []string) []string {
+	var filteredFiles []string
+	for _, file := range files {
+		if f.Match(file) {
+			filteredFiles = append(filteredFiles, file)
+		}
+	}
+	return filteredFiles
+}
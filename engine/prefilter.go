@@ -0,0 +1,161 @@
+package engine
+
+import (
+	"fmt"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/gocolly/colly"
+	"go-find-version/utils"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// prefilterBySize issues HEAD requests and drops files whose reported size
+// matches no blob their path ever held, so only promising files are
+// downloaded. The size is taken from Content-Length, or from the ETag if the
+// server embeds it there. Files the server reports as missing are dropped
+// unless keepMissing is set; any other failure keeps the file. Without
+// compareSizes only missing files are dropped, for files that are expected
+// to differ from their blob. The statuses and HEAD responses of dropped files
+// are returned, as they won't be requested again.
+func prefilterBySize(repository *CachedRepo, files []string, baseURI string, rules rewriteRules, keepMissing, compareSizes bool) ([]string, *fileCheckResult, error) {
+	skipped := newFileCheckResult()
+
+	history, err := loadIndex(repository)
+	if err != nil {
+		return files, skipped, err
+	}
+
+	utils.PrintInfo("Comparing file sizes with HEAD requests")
+
+	blobSizes := make(map[plumbing.Hash]int64)
+	knownSizes := func(file string) map[int64]bool {
+		sizes := make(map[int64]bool)
+		for _, interval := range history.Paths[file] {
+			size, ok := blobSizes[interval.Hash]
+			if !ok {
+				blob, err := repository.repo.BlobObject(interval.Hash)
+				if err != nil {
+					continue
+				}
+				size = blob.Size
+				blobSizes[interval.Hash] = size
+			}
+			sizes[size] = true
+		}
+		return sizes
+	}
+
+	c := newCollector(true)
+
+	var mu sync.Mutex
+	reported := make(map[string][]int64)
+	missing := make(map[string]bool)
+	evidence := make(map[string]fileEvidence)
+
+	c.OnResponse(func(r *colly.Response) {
+		sizes := responseSizes(r.Headers)
+		mu.Lock()
+		reported[r.Ctx.Get("filename")] = sizes
+		evidence[r.Ctx.Get("filename")] = newEvidence(r, nil)
+		mu.Unlock()
+	})
+
+	c.OnError(func(r *colly.Response, err error) {
+		if shouldRetry(r, err) && retryRequest(r) == nil {
+			return
+		}
+		if r.StatusCode == http.StatusNotFound || r.StatusCode == http.StatusGone {
+			mu.Lock()
+			missing[r.Ctx.Get("filename")] = true
+			evidence[r.Ctx.Get("filename")] = newEvidence(r, err)
+			mu.Unlock()
+		}
+	})
+
+	for _, file := range files {
+		fullURL, ok := fileURL(baseURI, file, rules)
+		if !ok {
+			continue
+		}
+		ctx := colly.NewContext()
+		ctx.Put("filename", file)
+		ctx.Put("url", fullURL)
+		c.Request("HEAD", fullURL, nil, ctx, nil)
+	}
+	c.Wait()
+
+	skip := func(file string, status fileStatus) {
+		skipped.Statuses[file] = status
+		skipped.Evidence[file] = append(skipped.Evidence[file], evidence[file])
+	}
+
+	var result []string
+	for _, file := range files {
+		if missing[file] && !keepMissing {
+			skip(file, errorStatus(evidence[file].StatusCode))
+			continue
+		}
+		if sizes := reported[file]; compareSizes && len(sizes) > 0 {
+			known := knownSizes(file)
+			matches := false
+			for _, size := range sizes {
+				matches = matches || known[size]
+			}
+			if !matches {
+				skip(file, statusSizeMismatch)
+				continue
+			}
+		}
+		result = append(result, file)
+	}
+
+	if compareSizes {
+		utils.PrintInfo(fmt.Sprintf("Skipping %d files whose size matches no known version", len(files)-len(result)))
+	} else {
+		utils.PrintInfo(fmt.Sprintf("Skipping %d files the server reports as missing", len(files)-len(result)))
+	}
+	return result, skipped, nil
+}
+
+// responseSizes returns the possible sizes of the unencoded file. An empty
+// result means the headers don't reveal the size.
+func responseSizes(headers *http.Header) []int64 {
+	if encoding := headers.Get("Content-Encoding"); encoding != "" && encoding != "identity" {
+		return nil
+	}
+	if length, err := strconv.ParseInt(headers.Get("Content-Length"), 10, 64); err == nil {
+		return []int64{length}
+	}
+	return etagSizes(headers.Get("ETag"))
+}
+
+// etagSizes extracts the file size from ETags built from file metadata.
+// Apache uses "inode-size-mtime" or "size-mtime", nginx uses "mtime-size",
+// all in hex, so a two part tag yields both candidates.
+func etagSizes(etag string) []int64 {
+	etag = strings.TrimPrefix(etag, "W/")
+	etag = strings.Trim(etag, `"`)
+	// mod_deflate and friends append the encoding
+	for _, suffix := range []string{"-gzip", "-br", "-deflate"} {
+		etag = strings.TrimSuffix(etag, suffix)
+	}
+
+	parts := strings.Split(etag, "-")
+	var candidates []string
+	switch len(parts) {
+	case 2:
+		candidates = parts
+	case 3:
+		candidates = parts[1:2]
+	}
+
+	var sizes []int64
+	for _, part := range candidates {
+		if size, err := strconv.ParseInt(part, 16, 64); err == nil {
+			sizes = append(sizes, size)
+		}
+	}
+	return sizes
+}
@@ -0,0 +1,207 @@
+package engine
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// frameworkProfile preselects the repository, the rewrite rules mapping it
+// onto the webroot and a curated list of files that are served verbatim and
+// change often enough to tell releases apart.
+type frameworkProfile struct {
+	Name       string
+	Repository string
+	Rewrite    []string
+	Files      []string
+}
+
+var frameworkProfiles = map[string]frameworkProfile{
+	"drupal": {
+		Name:       "Drupal",
+		Repository: "https://github.com/drupal/drupal.git",
+		Files: []string{
+			// Drupal 8 and later
+			"core/CHANGELOG.txt",
+			"core/INSTALL.txt",
+			"core/UPDATE.txt",
+			"core/MAINTAINERS.txt",
+			"core/COPYRIGHT.txt",
+			"core/misc/drupal.js",
+			"core/misc/drupal.init.js",
+			"core/misc/drupalSettingsLoader.js",
+			"core/misc/ajax.js",
+			"core/misc/announce.js",
+			"core/misc/autocomplete.js",
+			"core/misc/debounce.js",
+			"core/misc/states.js",
+			"core/misc/tabledrag.js",
+			"core/misc/dialog/dialog.js",
+			"core/misc/htmx/htmx-assets.js",
+			"core/assets/vendor/jquery/jquery.min.js",
+			"core/modules/ckeditor5/js/ckeditor5.js",
+			"core/themes/claro/css/base/elements.css",
+			"core/themes/olivero/css/base/base.css",
+			"core/themes/bartik/css/base/elements.css",
+			// Drupal 7
+			"CHANGELOG.txt",
+			"INSTALL.txt",
+			"MAINTAINERS.txt",
+			"misc/drupal.js",
+			"misc/ajax.js",
+			"misc/autocomplete.js",
+			"misc/tabledrag.js",
+			"misc/jquery.js",
+			"modules/system/system.base.css",
+			"themes/bartik/css/style.css",
+		},
+	},
+	"wordpress": {
+		Name:       "WordPress",
+		Repository: "https://github.com/WordPress/WordPress.git",
+		Files: []string{
+			"readme.html",
+			"license.txt",
+			"wp-includes/js/jquery/jquery.js",
+			"wp-includes/js/jquery/jquery-migrate.min.js",
+			"wp-includes/js/wp-emoji-release.min.js",
+			"wp-includes/js/wp-embed.min.js",
+			"wp-includes/js/admin-bar.min.js",
+			"wp-includes/js/tinymce/tinymce.min.js",
+			"wp-includes/css/dashicons.min.css",
+			"wp-includes/css/admin-bar.min.css",
+			"wp-includes/css/dist/block-library/style.min.css",
+			"wp-admin/css/common.min.css",
+			"wp-admin/css/login.min.css",
+			"wp-admin/js/common.min.js",
+			"wp-admin/js/password-strength-meter.min.js",
+		},
+	},
+	"joomla": {
+		Name:       "Joomla",
+		Repository: "https://github.com/joomla/joomla-cms.git",
+		Files: []string{
+			"README.txt",
+			"LICENSE.txt",
+			"htaccess.txt",
+			"web.config.txt",
+			"robots.txt.dist",
+			"administrator/manifests/files/joomla.xml",
+			"language/en-GB/en-GB.xml",
+			"language/en-GB/langmetadata.xml",
+			"administrator/language/en-GB/install.xml",
+			"media/system/js/core.js",
+			"media/system/js/mootools-core.js",
+			"media/jui/js/jquery.min.js",
+		},
+	},
+	"magento": {
+		Name:       "Magento",
+		Repository: "https://github.com/magento/magento2.git",
+		Rewrite:    []string{"strip:pub/"},
+		Files: []string{
+			"pub/errors/default/css/styles.css",
+			"pub/errors/default/images/logo.gif",
+			"pub/errors/default/images/favicon.ico",
+			"pub/errors/default/images/i_msg-error.gif",
+			"pub/errors/default/images/i_msg-note.gif",
+			"pub/errors/default/images/i_msg-success.gif",
+		},
+	},
+	"laravel": {
+		Name:       "Laravel",
+		Repository: "https://github.com/laravel/laravel.git",
+		Rewrite:    []string{"strip:public/"},
+		Files: []string{
+			"public/robots.txt",
+			"public/favicon.ico",
+			"public/web.config",
+			"public/css/app.css",
+			"public/js/app.js",
+			"public/mix-manifest.json",
+			"public/svg/403.svg",
+			"public/svg/404.svg",
+			"public/svg/500.svg",
+			"public/svg/503.svg",
+		},
+	},
+	"symfony": {
+		Name:       "Symfony",
+		Repository: "https://github.com/symfony/symfony.git",
+		// Bundle assets are published by assets:install
+		Rewrite: []string{
+			"regex:^src/Symfony/Bundle/FrameworkBundle/Resources/public/=bundles/framework/",
+			"regex:^src/Symfony/Bundle/WebProfilerBundle/Resources/public/=bundles/webprofiler/",
+		},
+		Files: []string{
+			"src/Symfony/Bundle/FrameworkBundle/Resources/public/css/body.css",
+			"src/Symfony/Bundle/FrameworkBundle/Resources/public/css/exception.css",
+			"src/Symfony/Bundle/FrameworkBundle/Resources/public/css/structure.css",
+			"src/Symfony/Bundle/WebProfilerBundle/Resources/public/favicon.ico",
+			"src/Symfony/Bundle/WebProfilerBundle/Resources/public/images/profiler/logo_symfony_profiler.gif",
+		},
+	},
+	"typo3": {
+		Name:       "TYPO3",
+		Repository: "https://github.com/TYPO3/typo3.git",
+		Files: []string{
+			"README.md",
+			"LICENSE.txt",
+			"INSTALL.md",
+			"typo3/sysext/backend/Resources/Public/Css/backend.css",
+			"typo3/sysext/backend/Resources/Public/Images/typo3_logo_orange.svg",
+			"typo3/sysext/core/Resources/Public/Icons/Extension.svg",
+			"typo3/sysext/core/Resources/Public/JavaScript/Contrib/jquery/jquery.min.js",
+			"typo3/sysext/install/Resources/Public/Css/install.css",
+		},
+	},
+	"nextcloud": {
+		Name:       "Nextcloud",
+		Repository: "https://github.com/nextcloud/server.git",
+		Files: []string{
+			"robots.txt",
+			"AUTHORS",
+			"COPYING",
+			"core/css/server.css",
+			"core/css/guest.css",
+			"core/css/apps.css",
+			"core/img/favicon.ico",
+			"core/img/logo/logo.svg",
+			"core/js/oc.js",
+			"dist/core-main.js",
+			"dist/core-common.js",
+		},
+	},
+	"gitlab": {
+		Name:       "GitLab",
+		Repository: "https://gitlab.com/gitlab-org/gitlab.git",
+		Rewrite:    []string{"strip:public/"},
+		Files: []string{
+			"public/robots.txt",
+			"public/404.html",
+			"public/422.html",
+			"public/500.html",
+			"public/502.html",
+			"public/503.html",
+			"public/deploy.html",
+			"public/favicon.png",
+		},
+	},
+}
+
+func findProfile(name string) (*frameworkProfile, error) {
+	profile, ok := frameworkProfiles[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown profile %q, expected one of %s", name, strings.Join(profileNames(), ", "))
+	}
+	return &profile, nil
+}
+
+func profileNames() []string {
+	names := make([]string, 0, len(frameworkProfiles))
+	for name := range frameworkProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
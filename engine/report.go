@@ -0,0 +1,231 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"net/url"
+	"os"
+	"sort"
+	"time"
+)
+
+type Report struct {
+	Repository    string                    `json:"repository"`
+	Name          string                    `json:"repository_name"`
+	Website       string                    `json:"website"`
+	Capture       string                    `json:"capture,omitempty"`
+	Local         string                    `json:"local_directory,omitempty"`
+	Divergence    *localDivergence          `json:"divergence,omitempty"`
+	Profile       string                    `json:"profile,omitempty"`
+	RewriteRules  []string                  `json:"rewrite_rules"`
+	Normalization []string                  `json:"normalization,omitempty"`
+	Files         []ReportFile              `json:"files"`
+	Statuses      map[string]fileStatus     `json:"file_statuses"`
+	Evidence      map[string][]fileEvidence `json:"evidence"`
+	Fetch         *ReportFetch              `json:"fetch,omitempty"`
+	Source        *ReportCommit             `json:"source,omitempty"`
+	Next          *ReportCommit             `json:"next,omitempty"`
+	Release       ReleaseRange              `json:"release"`
+	Consistent    []ReportRange             `json:"consistent_ranges"`
+	Unmatched     []string                  `json:"unmatched_files"`
+	Nearest       []ReportNearest           `json:"nearest_revisions,omitempty"`
+	TopCommits    []ReportCommit            `json:"top_commits"`
+}
+
+// ReportFile is a repository file found on the webserver.
+type ReportFile struct {
+	Path   string     `json:"path"`
+	URL    string     `json:"url"`
+	Hash   string     `json:"hash"`
+	Status fileStatus `json:"status"`
+	// Normalized files only matched the blob after normalization
+	Normalized bool `json:"normalized,omitempty"`
+	// Encoding lists the content codings removed before hashing
+	Encoding string `json:"encoding,omitempty"`
+	// Variant is the suffix of the pre-compressed file that was hashed
+	Variant string `json:"variant,omitempty"`
+}
+
+// ReportFetch records how the webserver was queried. Header values,
+// passwords, tokens and cookie values are left out.
+type ReportFetch struct {
+	Concurrency       int                 `json:"concurrency"`
+	Delay             string              `json:"delay"`
+	Jitter            string              `json:"jitter,omitempty"`
+	RequestsPerSecond float64             `json:"requests_per_second,omitempty"`
+	UserAgent         string              `json:"user_agent"`
+	Timeout           string              `json:"timeout"`
+	Retries           int                 `json:"retries"`
+	Headers           []string            `json:"headers,omitempty"`
+	Connection        *connectionSettings `json:"connection,omitempty"`
+}
+
+func buildReportFetch(settings fetchSettings) *ReportFetch {
+	result := &ReportFetch{
+		Concurrency:       settings.Parallelism,
+		Delay:             settings.Delay.String(),
+		RequestsPerSecond: settings.RequestsPerSecond,
+		UserAgent:         settings.UserAgent,
+		Timeout:           settings.Timeout.String(),
+		Retries:           settings.Retries,
+		Connection:        settings.Connection,
+	}
+	if settings.Jitter > 0 {
+		result.Jitter = settings.Jitter.String()
+	}
+	for name := range settings.Headers {
+		result.Headers = append(result.Headers, name)
+	}
+	sort.Strings(result.Headers)
+	return result
+}
+
+// ReportNearest is the historical blob closest to a file without an exact
+// match.
+type ReportNearest struct {
+	Path       string  `json:"path"`
+	Blob       string  `json:"blob"`
+	Similarity float64 `json:"similarity"`
+}
+
+type ReportRange struct {
+	First string `json:"first"`
+	Last  string `json:"last"`
+	Count int    `json:"count"`
+}
+
+type ReportCommit struct {
+	Hash    string       `json:"hash"`
+	Message string       `json:"message"`
+	Author  string       `json:"author"`
+	Date    time.Time    `json:"date"`
+	Link    string       `json:"link,omitempty"`
+	Score   *ReportScore `json:"score,omitempty"`
+}
+
+type ReportScore struct {
+	Score      int     `json:"score"`
+	Matched    int     `json:"matched"`
+	Mismatched int     `json:"mismatched"`
+	Unknown    int     `json:"unknown"`
+	Nearest    float64 `json:"nearest,omitempty"`
+}
+
+func newReportCommit(repo *git.Repository, links linkProvider, hash plumbing.Hash) *ReportCommit {
+	if hash.IsZero() {
+		return nil
+	}
+
+	result := &ReportCommit{
+		Hash: hash.String(),
+		Link: links.CommitLink(hash),
+	}
+	if commit, err := repo.CommitObject(hash); err == nil {
+		result.Message = firstLine(commit.Message)
+		result.Author = commit.Author.Name
+		result.Date = commit.Author.When
+	}
+	return result
+}
+
+func buildReport(repository *CachedRepo, links linkProvider, repoUri, websiteUri string, deployment *DeploymentRange, release ReleaseRange) *Report {
+	repo := repository.repo
+
+	report := &Report{
+		Repository: stripCredentials(repoUri),
+		Name:       repository.locator.DisplayName(),
+		Website:    stripCredentials(websiteUri),
+		Source:     newReportCommit(repo, links, deployment.Source),
+		Next:       newReportCommit(repo, links, deployment.Next),
+		Release:    release,
+		Consistent: []ReportRange{},
+		Unmatched:  []string{},
+		TopCommits: []ReportCommit{},
+	}
+
+	for _, r := range deployment.Consistent {
+		report.Consistent = append(report.Consistent, ReportRange{
+			First: r.First.String(),
+			Last:  r.Last.String(),
+			Count: r.Count,
+		})
+	}
+	report.Unmatched = append(report.Unmatched, deployment.Unmatched...)
+
+	for _, revision := range deployment.Nearest {
+		report.Nearest = append(report.Nearest, ReportNearest{
+			Path:       revision.Path,
+			Blob:       revision.Blob.String(),
+			Similarity: revision.Similarity,
+		})
+	}
+
+	for _, score := range deployment.Scores {
+		commit := newReportCommit(repo, links, score.Hash)
+		commit.Score = &ReportScore{
+			Score:      score.Score,
+			Matched:    score.Matched,
+			Mismatched: score.Mismatched,
+			Unknown:    score.Unknown,
+			Nearest:    score.Nearest,
+		}
+		report.TopCommits = append(report.TopCommits, *commit)
+
+		if report.Source != nil && report.Source.Hash == commit.Hash {
+			report.Source = commit
+		}
+	}
+
+	return report
+}
+
+// stripCredentials removes the user info from a URL written to a report, as
+// tokens are often passed as user name or password. Paths and scp-like
+// locations are returned unchanged.
+func stripCredentials(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.User == nil {
+		return rawURL
+	}
+	u.User = nil
+	return u.String()
+}
+
+func saveReport(report any, filename string) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode report: %v", err)
+	}
+
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		return fmt.Errorf("failed to write report: %v", err)
+	}
+	return nil
+}
+
+func buildReportFiles(checked *fileCheckResult, baseURI string, rules rewriteRules, normalizedFiles []string) []ReportFile {
+	normalized := make(map[string]bool, len(normalizedFiles))
+	for _, file := range normalizedFiles {
+		normalized[file] = true
+	}
+
+	files := make([]ReportFile, 0, len(checked.Hashes))
+	for file, hash := range checked.Hashes {
+		fullURL, _ := fileURL(baseURI, file, rules)
+		files = append(files, ReportFile{
+			Path:       file,
+			URL:        stripCredentials(fullURL + checked.Variants[file]),
+			Hash:       hash.String(),
+			Status:     checked.Statuses[file],
+			Normalized: normalized[file],
+			Encoding:   checked.Encodings[file],
+			Variant:    checked.Variants[file],
+		})
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Path < files[j].Path
+	})
+	return files
+}
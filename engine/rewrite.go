@@ -0,0 +1,107 @@
+package engine
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	rewriteStrip = "strip"
+	rewriteAdd   = "add"
+	rewriteRegex = "regex"
+)
+
+// rewriteRule is one step translating a repository path into a URL path.
+// Rules are written as "strip:public/", "add:blog/" or
+// "regex:^src/(.*)$=assets/$1".
+type rewriteRule struct {
+	Spec        string
+	Kind        string
+	Prefix      string
+	Pattern     *regexp.Regexp
+	Replacement string
+}
+
+// rewriteRules are applied in order. A strip rule drops paths outside its
+// prefix, since they are not below the webroot; add rules always apply and
+// regex rules only rewrite the paths they match.
+type rewriteRules []rewriteRule
+
+func parseRewriteRule(spec string) (rewriteRule, error) {
+	kind, value, ok := strings.Cut(spec, ":")
+	if !ok {
+		return rewriteRule{}, fmt.Errorf("rewrite rule %q has no kind, expected strip:, add: or regex:", spec)
+	}
+
+	rule := rewriteRule{Spec: spec, Kind: kind}
+	switch kind {
+	case rewriteStrip, rewriteAdd:
+		rule.Prefix = normalizePrefix(value)
+	case rewriteRegex:
+		pattern, replacement, ok := strings.Cut(value, "=")
+		if !ok {
+			return rewriteRule{}, fmt.Errorf("regex rule %q needs the form regex:<pattern>=<replacement>", spec)
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return rewriteRule{}, fmt.Errorf("invalid regex in rule %q: %v", spec, err)
+		}
+		rule.Pattern = re
+		rule.Replacement = replacement
+	default:
+		return rewriteRule{}, fmt.Errorf("unknown rewrite rule kind %q, expected strip, add or regex", kind)
+	}
+	return rule, nil
+}
+
+func parseRewriteRules(specs []string) (rewriteRules, error) {
+	var rules rewriteRules
+	for _, spec := range specs {
+		rule, err := parseRewriteRule(spec)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// normalizePrefix turns "/public", "public" and "public/" into "public/".
+func normalizePrefix(prefix string) string {
+	prefix = strings.Trim(prefix, "/")
+	if prefix == "" {
+		return ""
+	}
+	return prefix + "/"
+}
+
+// WebPath returns the URL path of a repository file, or false if the file is
+// not below the webroot.
+func (r rewriteRules) WebPath(file string) (string, bool) {
+	file = strings.TrimPrefix(file, "/")
+	for _, rule := range r {
+		switch rule.Kind {
+		case rewriteStrip:
+			if !strings.HasPrefix(file, rule.Prefix) {
+				return "", false
+			}
+			file = strings.TrimPrefix(file, rule.Prefix)
+		case rewriteAdd:
+			file = rule.Prefix + file
+		case rewriteRegex:
+			if rule.Pattern.MatchString(file) {
+				file = rule.Pattern.ReplaceAllString(file, rule.Replacement)
+			}
+		}
+	}
+	return file, true
+}
+
+func (r rewriteRules) Specs() []string {
+	specs := make([]string, 0, len(r))
+	for _, rule := range r {
+		specs = append(specs, rule.Spec)
+	}
+	return specs
+}
@@ -0,0 +1,73 @@
+package engine
+
+import (
+	"testing"
+)
+
+func TestParseRewriteRule(t *testing.T) {
+	tests := []struct {
+		spec    string
+		kind    string
+		prefix  string
+		wantErr bool
+	}{
+		{spec: "strip:public/", kind: rewriteStrip, prefix: "public/"},
+		{spec: "strip:/public", kind: rewriteStrip, prefix: "public/"},
+		{spec: "add:blog", kind: rewriteAdd, prefix: "blog/"},
+		{spec: "add:", kind: rewriteAdd, prefix: ""},
+		{spec: "regex:^src/(.*)$=assets/$1", kind: rewriteRegex},
+		{spec: "public/", wantErr: true},
+		{spec: "move:public/", wantErr: true},
+		{spec: "regex:^src/", wantErr: true},
+		{spec: "regex:(=x", wantErr: true},
+	}
+	for _, test := range tests {
+		rule, err := parseRewriteRule(test.spec)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("parseRewriteRule(%q) succeeded, want an error", test.spec)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseRewriteRule(%q) failed: %v", test.spec, err)
+			continue
+		}
+		if rule.Kind != test.kind || rule.Prefix != test.prefix || rule.Spec != test.spec {
+			t.Errorf("parseRewriteRule(%q) = %+v, want kind %q and prefix %q", test.spec, rule, test.kind, test.prefix)
+		}
+	}
+}
+
+func TestWebPath(t *testing.T) {
+	tests := []struct {
+		name  string
+		specs []string
+		file  string
+		want  string
+		ok    bool
+	}{
+		{"no rules", nil, "index.php", "index.php", true},
+		{"leading slash", nil, "/index.php", "index.php", true},
+		{"strip", []string{"strip:public"}, "public/index.php", "index.php", true},
+		{"strip outside webroot", []string{"strip:public"}, "src/Kernel.php", "", false},
+		{"strip needs a whole segment", []string{"strip:public"}, "publicity.txt", "", false},
+		{"add", []string{"add:blog"}, "index.php", "blog/index.php", true},
+		{"strip then add", []string{"strip:web/", "add:shop/"}, "web/app.js", "shop/app.js", true},
+		{"regex", []string{"regex:^src/(.*)$=assets/$1"}, "src/app.js", "assets/app.js", true},
+		{"regex without match", []string{"regex:^src/(.*)$=assets/$1"}, "lib/app.js", "lib/app.js", true},
+		{"rules apply in order", []string{"add:public/", "strip:public/"}, "app.js", "app.js", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rules, err := parseRewriteRules(test.specs)
+			if err != nil {
+				t.Fatalf("parseRewriteRules(%v) failed: %v", test.specs, err)
+			}
+			got, ok := rules.WebPath(test.file)
+			if got != test.want || ok != test.ok {
+				t.Errorf("WebPath(%q) = %q, %v, want %q, %v", test.file, got, ok, test.want, test.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package engine
+
+import (
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"slices"
+	"sort"
+)
+
+// linearHistory orders every commit reachable from a branch or tag for
+// indexing. Other references of the mirror, like the pull request heads and
+// merge previews GitHub publishes, are left out as they were never part of the
+// project's history. The indexed commits that are still reachable are kept as
+// a prefix, and their count is returned along with the commits to append.
+func linearHistory(repo *git.Repository, indexed []historyCommit) (int, []*object.Commit, error) {
+	refs, err := repo.References()
+	if err != nil {
+		return 0, nil, err
+	}
+	defer refs.Close()
+
+	var stack []*object.Commit
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Type() != plumbing.HashReference || !(ref.Name().IsBranch() || ref.Name().IsTag()) {
+			return nil
+		}
+		hash := ref.Hash()
+		if tag, err := repo.TagObject(hash); err == nil {
+			commit, err := tag.Commit()
+			if err != nil {
+				// Tags on trees or blobs have no history
+				return nil
+			}
+			hash = commit.Hash
+		}
+		commit, err := repo.CommitObject(hash)
+		if err != nil {
+			return nil
+		}
+		stack = append(stack, commit)
+		return nil
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+
+	// Older tips first, so long lived branches come before the ones forked
+	// from them. Ties are broken by hash to keep the order stable across runs.
+	sort.Slice(stack, func(i, j int) bool {
+		if !stack[i].Committer.When.Equal(stack[j].Committer.When) {
+			return stack[i].Committer.When.Before(stack[j].Committer.When)
+		}
+		return stack[i].Hash.String() < stack[j].Hash.String()
+	})
+	tips := make([]plumbing.Hash, 0, len(stack))
+	for _, commit := range stack {
+		tips = append(tips, commit.Hash)
+	}
+
+	commits := make(map[plumbing.Hash]*object.Commit)
+	parents := make(map[plumbing.Hash][]plumbing.Hash)
+	for len(stack) > 0 {
+		commit := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if commits[commit.Hash] != nil {
+			continue
+		}
+		commits[commit.Hash] = commit
+
+		for _, parent := range commit.ParentHashes {
+			parentCommit := commits[parent]
+			if parentCommit == nil {
+				parentCommit, err = repo.CommitObject(parent)
+				if err != nil {
+					// Shallow clones end at commits without their parents
+					continue
+				}
+				stack = append(stack, parentCommit)
+			}
+			parents[commit.Hash] = append(parents[commit.Hash], parent)
+		}
+	}
+
+	prefix := 0
+	emitted := make(map[plumbing.Hash]bool, len(indexed))
+	for prefix < len(indexed) && commits[indexed[prefix].Hash] != nil {
+		emitted[indexed[prefix].Hash] = true
+		prefix++
+	}
+
+	order := lineOrder(tips, parents, emitted)
+	result := make([]*object.Commit, 0, len(order))
+	for _, hash := range order {
+		result = append(result, commits[hash])
+	}
+	return prefix, result, nil
+}
+
+// lineOrder returns the commits reachable from tips that are not emitted yet,
+// parents before children. Each first-parent line is kept together, so a
+// commit mostly follows its own parent instead of a commit of another branch
+// that happens to be close in time. Merged lines are placed before the merge.
+func lineOrder(tips []plumbing.Hash, parents map[plumbing.Hash][]plumbing.Hash, emitted map[plumbing.Hash]bool) []plumbing.Hash {
+	var order []plumbing.Hash
+
+	var emitLine func(tip plumbing.Hash)
+	emitLine = func(tip plumbing.Hash) {
+		var line []plumbing.Hash
+		for hash := tip; !emitted[hash]; hash = parents[hash][0] {
+			line = append(line, hash)
+			if len(parents[hash]) == 0 {
+				break
+			}
+		}
+
+		for i := len(line) - 1; i >= 0; i-- {
+			hash := line[i]
+			if len(parents[hash]) > 1 {
+				for _, parent := range parents[hash][1:] {
+					emitLine(parent)
+				}
+			}
+			emitted[hash] = true
+			order = append(order, hash)
+		}
+	}
+
+	for _, tip := range tips {
+		emitLine(tip)
+	}
+	return order
+}
+
+// scoreCommits compares the webserver hashes against the tree of every commit
+// in the history and tallies exact matches, contradictions and paths missing
+// from the commit. Commits holding the nearest revision of a file without an
+// exact match don't count it as a contradiction and gain its similarity as
+// evidence instead.
+func scoreCommits(history *blobHistory, webserverHashes map[string]plumbing.Hash, normalizedBlobs map[string][]plumbing.Hash, nearest map[string]nearestRevision) []CommitScore {
+	scores := make([]CommitScore, 0, len(history.Commits))
+
+	for index, commit := range history.Commits {
+		score := CommitScore{
+			Hash: commit.Hash,
+			Time: commit.When,
+		}
+		for file, hash := range webserverHashes {
+			blob, exists := history.blobAt(file, index)
+			switch {
+			case !exists:
+				score.Unknown++
+			case blob == hash, slices.Contains(normalizedBlobs[file], blob):
+				score.Matched++
+			default:
+				// Holding the nearest revision is evidence, not a contradiction
+				if revision, ok := nearest[file]; ok && revision.Blob == blob {
+					score.Nearest += revision.Similarity
+				} else {
+					score.Mismatched++
+				}
+			}
+		}
+		score.Score = score.Matched - score.Mismatched
+		scores = append(scores, score)
+	}
+
+	return scores
+}
+
+func sortCommitScores(scores []CommitScore) {
+	sort.Slice(scores, func(i, j int) bool {
+		if scores[i].Score != scores[j].Score {
+			return scores[i].Score > scores[j].Score
+		}
+		if scores[i].Nearest != scores[j].Nearest {
+			return scores[i].Nearest > scores[j].Nearest
+		}
+		if scores[i].Mismatched != scores[j].Mismatched {
+			return scores[i].Mismatched < scores[j].Mismatched
+		}
+		return scores[i].Time.After(scores[j].Time)
+	})
+}
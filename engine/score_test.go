@@ -0,0 +1,53 @@
+package engine
+
+import (
+	"github.com/go-git/go-git/v5/plumbing"
+	"reflect"
+	"testing"
+)
+
+func TestLineOrder(t *testing.T) {
+	// 1 is the root; 2, 3 and 5 continue the main line, 4 branches off 1 and
+	// is merged by 5; 6 and 7 form a branch off 2 that was never merged
+	parents := map[plumbing.Hash][]plumbing.Hash{
+		testHash(2): {testHash(1)},
+		testHash(3): {testHash(2)},
+		testHash(4): {testHash(1)},
+		testHash(5): {testHash(3), testHash(4)},
+		testHash(6): {testHash(2)},
+		testHash(7): {testHash(6)},
+	}
+
+	tests := []struct {
+		name    string
+		tips    []int
+		emitted []int
+		want    []int
+	}{
+		{"merged line before the merge", []int{5}, nil, []int{1, 2, 3, 4, 5}},
+		{"branches kept together", []int{7, 5}, nil, []int{1, 2, 6, 7, 3, 4, 5}},
+		{"tips already reached", []int{5, 3, 4}, nil, []int{1, 2, 3, 4, 5}},
+		{"only new commits", []int{7, 5}, []int{1, 2, 3}, []int{6, 7, 4, 5}},
+		{"nothing new", []int{3}, []int{1, 2, 3}, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var tips []plumbing.Hash
+			for _, tip := range test.tips {
+				tips = append(tips, testHash(tip))
+			}
+			emitted := make(map[plumbing.Hash]bool)
+			for _, n := range test.emitted {
+				emitted[testHash(n)] = true
+			}
+			var want []plumbing.Hash
+			for _, n := range test.want {
+				want = append(want, testHash(n))
+			}
+
+			if got := lineOrder(tips, parents, emitted); !reflect.DeepEqual(got, want) {
+				t.Errorf("lineOrder(%v) = %v, want %v", test.tips, got, want)
+			}
+		})
+	}
+}
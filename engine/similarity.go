@@ -0,0 +1,134 @@
+package engine
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/go-git/go-git/v5/plumbing"
+	"go-find-version/utils"
+	"io"
+	"sort"
+)
+
+// minSimilarity is the lowest similarity reported as a nearest revision.
+const minSimilarity = 0.5
+
+// nearestRevision is the historical blob of a path closest to a fetched file
+// that matched no blob exactly.
+type nearestRevision struct {
+	Path       string
+	Blob       plumbing.Hash
+	Similarity float64
+}
+
+// findNearestRevisions compares every fetched file without an exact match
+// against all blobs its path ever held and keeps the most similar one.
+func findNearestRevisions(repository *CachedRepo, checked *fileCheckResult) (map[string]nearestRevision, error) {
+	history, err := loadIndex(repository)
+	if err != nil {
+		return nil, err
+	}
+
+	utils.PrintInfo("Finding nearest revisions of unmatched files")
+
+	nearest := make(map[string]nearestRevision)
+	for file, hash := range checked.Hashes {
+		body, ok := checked.Bodies[file]
+		if !ok || len(history.rangesFor(file, hash)) > 0 {
+			continue
+		}
+
+		units := splitUnits(body)
+		best := nearestRevision{Path: file}
+		seen := make(map[plumbing.Hash]bool)
+		for _, interval := range history.Paths[file] {
+			if seen[interval.Hash] {
+				continue
+			}
+			seen[interval.Hash] = true
+
+			data, err := readBlob(repository, interval.Hash)
+			if err != nil {
+				utils.PrintWarning(fmt.Sprintf("Failed to read blob %s of %s: %v", interval.Hash, file, err))
+				continue
+			}
+			if similarity := unitSimilarity(units, splitUnits(data)); similarity > best.Similarity {
+				best.Blob = interval.Hash
+				best.Similarity = similarity
+			}
+		}
+
+		if best.Similarity >= minSimilarity {
+			nearest[file] = best
+		}
+	}
+
+	return nearest, nil
+}
+
+// splitUnits splits content into lines, or into statements for minified
+// content that has too few lines to compare meaningfully. Surrounding
+// whitespace is ignored and blank units are dropped.
+func splitUnits(data []byte) []string {
+	separators := func(r rune) bool {
+		return r == '\n'
+	}
+	if bytes.Count(data, []byte("\n")) < 8 {
+		separators = func(r rune) bool {
+			return r == '\n' || r == ';' || r == '}'
+		}
+	}
+
+	var units []string
+	for _, unit := range bytes.FieldsFunc(data, separators) {
+		if unit = bytes.TrimSpace(unit); len(unit) > 0 {
+			units = append(units, string(unit))
+		}
+	}
+	return units
+}
+
+// unitSimilarity is the Dice coefficient of the two multisets of units.
+func unitSimilarity(a, b []string) float64 {
+	if len(a)+len(b) == 0 {
+		return 1
+	}
+
+	counts := make(map[string]int, len(a))
+	for _, unit := range a {
+		counts[unit]++
+	}
+
+	common := 0
+	for _, unit := range b {
+		if counts[unit] > 0 {
+			counts[unit]--
+			common++
+		}
+	}
+	return float64(2*common) / float64(len(a)+len(b))
+}
+
+func readBlob(repository *CachedRepo, hash plumbing.Hash) ([]byte, error) {
+	blob, err := repository.repo.BlobObject(hash)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := blob.Reader()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	return io.ReadAll(reader)
+}
+
+func sortedNearest(nearest map[string]nearestRevision) []nearestRevision {
+	result := make([]nearestRevision, 0, len(nearest))
+	for _, revision := range nearest {
+		result = append(result, revision)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Path < result[j].Path
+	})
+	return result
+}
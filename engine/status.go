@@ -0,0 +1,114 @@
+package engine
+
+import (
+	"fmt"
+	"go-find-version/utils"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// fileStatus is the outcome of checking a single file on the webserver.
+type fileStatus string
+
+const (
+	statusMatched      fileStatus = "matched"
+	statusUnmatched    fileStatus = "unmatched"
+	statusNotFound     fileStatus = "not_found"
+	statusForbidden    fileStatus = "forbidden"
+	statusServerError  fileStatus = "server_error"
+	statusNetworkError fileStatus = "network_error"
+	statusSoft404      fileStatus = "soft_404"
+	// statusSizeMismatch files were skipped by the HEAD prefilter, as their
+	// size matches no version of the path
+	statusSizeMismatch fileStatus = "size_mismatch"
+	// statusNotCaptured files were served, but the capture holds no body,
+	// e.g. browsers answered from their cache after a 304
+	statusNotCaptured fileStatus = "not_captured"
+)
+
+// fileStatuses lists the statuses in display order.
+var fileStatuses = []fileStatus{
+	statusMatched,
+	statusUnmatched,
+	statusNotFound,
+	statusForbidden,
+	statusServerError,
+	statusNetworkError,
+	statusSoft404,
+	statusSizeMismatch,
+	statusNotCaptured,
+}
+
+func (s fileStatus) String() string {
+	return strings.ReplaceAll(string(s), "_", " ")
+}
+
+// errorStatus classifies a failed request. Status code 0 means no response
+// was received.
+func errorStatus(statusCode int) fileStatus {
+	switch {
+	case statusCode == 0:
+		return statusNetworkError
+	case statusCode == http.StatusUnauthorized,
+		statusCode == http.StatusForbidden,
+		statusCode == http.StatusProxyAuthRequired,
+		statusCode == http.StatusUnavailableForLegalReasons:
+		return statusForbidden
+	case statusCode == http.StatusTooManyRequests, statusCode >= 500:
+		return statusServerError
+	default:
+		return statusNotFound
+	}
+}
+
+// resolveStatuses marks every downloaded file as matched or unmatched, once
+// the files matching no historical version are known.
+func (r *fileCheckResult) resolveStatuses(unmatched []string) {
+	isUnmatched := make(map[string]bool, len(unmatched))
+	for _, file := range unmatched {
+		isUnmatched[file] = true
+	}
+	for file := range r.Hashes {
+		if isUnmatched[file] {
+			r.Statuses[file] = statusUnmatched
+		} else {
+			r.Statuses[file] = statusMatched
+		}
+	}
+}
+
+// countStatuses returns the number of files per status.
+func countStatuses(statuses map[string]fileStatus) map[fileStatus]int {
+	counts := make(map[fileStatus]int)
+	for _, status := range statuses {
+		counts[status]++
+	}
+	return counts
+}
+
+func displayFileStatuses(statuses map[string]fileStatus) {
+	counts := countStatuses(statuses)
+	var parts []string
+	for _, status := range fileStatuses {
+		if counts[status] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[status], status))
+		}
+	}
+	if len(parts) == 0 {
+		return
+	}
+	utils.PrintInfo("Checked files: " + strings.Join(parts, ", "))
+
+	// Files that may be served but could not be checked deserve a look
+	var failed []string
+	for file, status := range statuses {
+		if status == statusServerError || status == statusNetworkError {
+			failed = append(failed, file)
+		}
+	}
+	if len(failed) > 0 {
+		sort.Strings(failed)
+		utils.PrintWarning(fmt.Sprintf("%d files failed after retries: %s", len(failed), strings.Join(failed[:min(5, len(failed))], ", ")))
+	}
+}
@@ -0,0 +1,170 @@
+package engine
+
+import (
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+	"sort"
+	"strings"
+)
+
+// ReleaseRange names the release tags enclosing a commit. Previous is the
+// nearest tag the commit already contains, Next the nearest tag containing it.
+// Candidates lists the tags of several consistent commits, any of which may
+// be deployed.
+type ReleaseRange struct {
+	Previous   []string `json:"previous,omitempty"`
+	Next       []string `json:"next,omitempty"`
+	Candidates []string `json:"candidates,omitempty"`
+	Exact      bool     `json:"exact"`
+}
+
+func (r ReleaseRange) String() string {
+	previous := strings.Join(r.Previous, ", ")
+	next := strings.Join(r.Next, ", ")
+
+	switch {
+	case r.Exact:
+		return previous
+	case len(r.Candidates) > 0:
+		return "one of " + strings.Join(r.Candidates, ", ")
+	case previous != "" && next != "":
+		return "between " + previous + " and " + next
+	case previous != "":
+		return "after " + previous + " (unreleased)"
+	case next != "":
+		return "before " + next
+	}
+	return "no release tags found"
+}
+
+// loadTagCommits maps every tagged commit to the names of its tags, peeling
+// annotated tags down to the commit they point at.
+func loadTagCommits(repo *git.Repository) (map[plumbing.Hash][]string, error) {
+	tags, err := repo.Tags()
+	if err != nil {
+		return nil, err
+	}
+	defer tags.Close()
+
+	result := make(map[plumbing.Hash][]string)
+	err = tags.ForEach(func(ref *plumbing.Reference) error {
+		hash := ref.Hash()
+		if tag, err := repo.TagObject(hash); err == nil {
+			commit, err := tag.Commit()
+			if err != nil {
+				// Tags on trees or blobs can't enclose a commit
+				return nil
+			}
+			hash = commit.Hash
+		}
+		result[hash] = append(result[hash], ref.Name().Short())
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, names := range result {
+		sort.Strings(names)
+	}
+	return result, nil
+}
+
+// findReleaseRange names the release of hash. A single tagged commit among
+// the consistent commits is reported as the exact release and several as candidates.
+// Only when none is tagged are the tags enclosing hash looked up.
+func findReleaseRange(repo *git.Repository, hash plumbing.Hash, consistent []plumbing.Hash) (ReleaseRange, error) {
+	var release ReleaseRange
+
+	tagCommits, err := loadTagCommits(repo)
+	if err != nil {
+		return release, err
+	}
+
+	commit, err := repo.CommitObject(hash)
+	if err != nil {
+		return release, err
+	}
+
+	if len(consistent) == 0 {
+		consistent = []plumbing.Hash{hash}
+	}
+	var tagged []*object.Commit
+	for _, candidate := range consistent {
+		if _, ok := tagCommits[candidate]; !ok {
+			continue
+		}
+		if taggedCommit, err := repo.CommitObject(candidate); err == nil {
+			tagged = append(tagged, taggedCommit)
+		}
+	}
+
+	switch len(tagged) {
+	case 0:
+	case 1:
+		release.Previous = tagCommits[tagged[0].Hash]
+		release.Next = release.Previous
+		release.Exact = true
+		return release, nil
+	default:
+		sort.Slice(tagged, func(i, j int) bool {
+			return tagged[i].Committer.When.Before(tagged[j].Committer.When)
+		})
+		for _, taggedCommit := range tagged {
+			release.Candidates = append(release.Candidates, tagCommits[taggedCommit.Hash]...)
+		}
+		return release, nil
+	}
+
+	// Nearest tagged ancestor, walking back in committer time order
+	ancestors := object.NewCommitIterCTime(commit, nil, nil)
+	_ = ancestors.ForEach(func(c *object.Commit) error {
+		if names, ok := tagCommits[c.Hash]; ok {
+			release.Previous = names
+			return storer.ErrStop
+		}
+		return nil
+	})
+
+	// Oldest tag that has the commit in its history
+	var candidates []*object.Commit
+	for tagHash := range tagCommits {
+		tagCommit, err := repo.CommitObject(tagHash)
+		if err != nil || tagCommit.Committer.When.Before(commit.Committer.When) {
+			continue
+		}
+		candidates = append(candidates, tagCommit)
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].Committer.When.Before(candidates[j].Committer.When)
+	})
+
+	for _, candidate := range candidates {
+		if containsCommit(candidate, commit) {
+			release.Next = tagCommits[candidate.Hash]
+			break
+		}
+	}
+
+	return release, nil
+}
+
+// containsCommit reports whether target is reachable from tip. Parents older
+// than target are not followed, since they can't lead back to it.
+func containsCommit(tip, target *object.Commit) bool {
+	isTarget := object.CommitFilter(func(c *object.Commit) bool {
+		return c.Hash == target.Hash
+	})
+	isTooOld := object.CommitFilter(func(c *object.Commit) bool {
+		return c.Committer.When.Before(target.Committer.When)
+	})
+
+	iter := object.NewFilterCommitIter(tip, &isTarget, &isTooOld)
+	defer iter.Close()
+
+	_, err := iter.Next()
+	return err == nil
+}
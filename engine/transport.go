@@ -0,0 +1,218 @@
+package engine
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// connectionSettings describe how the webserver is reached. Secrets stay in
+// the transport, jar and authorization header and are left out of the
+// exported fields recorded in reports.
+type connectionSettings struct {
+	Proxy         string `json:"proxy,omitempty"`
+	CABundle      string `json:"ca_bundle,omitempty"`
+	Insecure      bool   `json:"insecure_tls,omitempty"`
+	ClientCert    string `json:"client_cert,omitempty"`
+	Auth          string `json:"auth,omitempty"`
+	AuthUser      string `json:"auth_user,omitempty"`
+	CookieFile    string `json:"cookie_file,omitempty"`
+	CookieCount   int    `json:"cookie_count,omitempty"`
+	transport     *http.Transport
+	jar           *cookiejar.Jar
+	authorization string
+}
+
+type connectionOptions struct {
+	Proxy       string
+	CABundle    string
+	Insecure    bool
+	ClientCert  string
+	ClientKey   string
+	BasicAuth   string
+	BearerToken string
+	CookieFile  string
+	WebsiteUrl  string
+}
+
+func newConnectionSettings(options connectionOptions) (*connectionSettings, error) {
+	settings := &connectionSettings{
+		CABundle:   options.CABundle,
+		Insecure:   options.Insecure,
+		ClientCert: options.ClientCert,
+		CookieFile: options.CookieFile,
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	tlsConfig := &tls.Config{InsecureSkipVerify: options.Insecure}
+
+	if options.Proxy != "" {
+		proxyURL, err := url.Parse(options.Proxy)
+		if err != nil || proxyURL.Host == "" {
+			return nil, fmt.Errorf("invalid proxy url %q", options.Proxy)
+		}
+		switch proxyURL.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return nil, fmt.Errorf("unsupported proxy scheme %q, expected http, https or socks5", proxyURL.Scheme)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+		settings.Proxy = redactURL(proxyURL)
+	}
+
+	if options.CABundle != "" {
+		pem, err := os.ReadFile(options.CABundle)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %v", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", options.CABundle)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if options.ClientCert != "" {
+		keyFile := options.ClientKey
+		if keyFile == "" {
+			// Certificate and key in one PEM file
+			keyFile = options.ClientCert
+		}
+		certificate, err := tls.LoadX509KeyPair(options.ClientCert, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	transport.TLSClientConfig = tlsConfig
+	settings.transport = transport
+
+	switch {
+	case options.BasicAuth != "" && options.BearerToken != "":
+		return nil, fmt.Errorf("basic auth and bearer token can't be combined")
+	case options.BasicAuth != "":
+		user, _, ok := strings.Cut(options.BasicAuth, ":")
+		if !ok {
+			return nil, fmt.Errorf("basic auth must have the form user:password")
+		}
+		settings.Auth = "basic"
+		settings.AuthUser = user
+		settings.authorization = "Basic " + base64.StdEncoding.EncodeToString([]byte(options.BasicAuth))
+	case options.BearerToken != "":
+		settings.Auth = "bearer"
+		settings.authorization = "Bearer " + options.BearerToken
+	}
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+	settings.jar = jar
+
+	if options.CookieFile != "" {
+		cookies, err := readCookieFile(options.CookieFile, options.WebsiteUrl)
+		if err != nil {
+			return nil, err
+		}
+		for u, list := range cookies {
+			jar.SetCookies(u, list)
+			settings.CookieCount += len(list)
+		}
+	}
+
+	return settings, nil
+}
+
+func redactURL(u *url.URL) string {
+	redacted := *u
+	if redacted.User != nil {
+		redacted.User = url.User(redacted.User.Username())
+	}
+	return redacted.String()
+}
+
+// readCookieFile reads cookies in the Netscape cookies.txt format exported by
+// browsers, curl and Burp. Lines of the form "name=value; name2=value2", as
+// copied from a Cookie header, are set for the website.
+func readCookieFile(filename, websiteUrl string) (map[*url.URL][]*http.Cookie, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open cookie file: %v", err)
+	}
+	defer file.Close()
+
+	website, err := url.Parse(websiteUrl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid website url: %v", err)
+	}
+
+	result := make(map[*url.URL][]*http.Cookie)
+	hosts := make(map[string]*url.URL)
+	urlFor := func(scheme, host, path string) *url.URL {
+		key := scheme + "://" + host + path
+		if u, ok := hosts[key]; ok {
+			return u
+		}
+		u := &url.URL{Scheme: scheme, Host: host, Path: path}
+		hosts[key] = u
+		return u
+	}
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		// curl marks HttpOnly cookies with a "#HttpOnly_" domain prefix
+		line = strings.TrimPrefix(line, "#HttpOnly_")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		if len(fields) == 7 {
+			domain := strings.TrimPrefix(fields[0], ".")
+			scheme := "http"
+			if strings.EqualFold(fields[3], "TRUE") {
+				scheme = "https"
+			}
+			cookie := &http.Cookie{
+				Name:   fields[5],
+				Value:  fields[6],
+				Path:   fields[2],
+				Domain: fields[0],
+				Secure: scheme == "https",
+			}
+			if expires, err := strconv.ParseInt(fields[4], 10, 64); err == nil && expires > 0 {
+				cookie.Expires = time.Unix(expires, 0)
+			}
+			u := urlFor(scheme, domain, fields[2])
+			result[u] = append(result[u], cookie)
+			continue
+		}
+
+		for _, pair := range strings.Split(line, ";") {
+			name, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			if !ok || name == "" {
+				continue
+			}
+			u := urlFor(website.Scheme, website.Host, "/")
+			result[u] = append(result[u], &http.Cookie{Name: name, Value: value, Path: "/"})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read cookie file: %v", err)
+	}
+	return result, nil
+}
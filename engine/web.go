@@ -15,20 +15,28 @@ import (
 )
 
 type CommitScore struct {
-	Hash  plumbing.Hash
-	Score int
-	Time  time.Time
+	Hash       plumbing.Hash
+	Score      int
+	Matched    int
+	Mismatched int
+	Unknown    int
+	// Nearest sums the similarity of nearest revisions held by the commit
+	Nearest float64
+	Time    time.Time
 }
 
 type fileCheckedMsg struct {
-	error bool
+	// status is empty for downloaded files, which are only classified as
+	// matched or unmatched once the history was searched
+	status fileStatus
 }
 
 type webFetchModel struct {
-	progress  progress.Model
-	total     int
-	done      int
-	doneError int
+	progress   progress.Model
+	total      int
+	done       int
+	downloaded int
+	statuses   map[fileStatus]int
 }
 
 func (m *webFetchModel) Init() tea.Cmd {
@@ -39,8 +47,10 @@ func (m *webFetchModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case fileCheckedMsg:
 		m.done++
-		if msg.error {
-			m.doneError++
+		if msg.status == "" {
+			m.downloaded++
+		} else {
+			m.statuses[msg.status]++
 		}
 
 		percent := float64(m.done) / float64(m.total)
@@ -57,34 +67,107 @@ func (m *webFetchModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 func (m *webFetchModel) View() string {
 	percent := float64(m.done) / float64(m.total)
 	return fmt.Sprintf(
-		"%s %d/%d files checked\n✅  %d\n❌  %d",
+		"%s %d/%d files checked\n✅  %d downloaded\n❔  %d not found\n🔒  %d forbidden\n💥  %d server errors\n🔌  %d network errors\n🚫  %d soft-404 responses discarded",
 		m.progress.ViewAs(percent),
 		m.done,
 		m.total,
-		m.done-m.doneError,
-		m.doneError,
+		m.downloaded,
+		m.statuses[statusNotFound],
+		m.statuses[statusForbidden],
+		m.statuses[statusServerError],
+		m.statuses[statusNetworkError],
+		m.statuses[statusSoft404],
 	)
 }
 
-func checkFileHashes(files []string, baseURI string) map[string]plumbing.Hash {
-	utils.PrintInfo("Checking files on webserver")
-	c := colly.NewCollector(
-		colly.Async(true),
-		colly.UserAgent("FileChecker/1.0"),
-	)
+// fileCheckResult holds the hashes of the files found on the webserver.
+type fileCheckResult struct {
+	Hashes map[string]plumbing.Hash
+	// NormalizedHashes are the hashes of the normalized response bodies, only
+	// filled when a normalizer is used
+	NormalizedHashes map[string]plumbing.Hash
+	// NormalizedBlobs lists every blob of a path that matched only after
+	// normalization. Blobs differing in line endings or whitespace all match.
+	NormalizedBlobs map[string][]plumbing.Hash
+	// Bodies are only kept when requested, for similarity matching
+	Bodies map[string][]byte
+	// Encodings hold the content codings removed before hashing
+	Encodings map[string]string
+	// Variants hold the suffix of the pre-compressed variant that was hashed
+	Variants map[string]string
+	// Statuses hold the outcome per file. Downloaded files are added by
+	// resolveStatuses.
+	Statuses map[string]fileStatus
+	// Evidence holds every response per file, including failed variants
+	Evidence  map[string][]fileEvidence
+	Discarded int
+}
 
-	c.Limit(&colly.LimitRule{
-		DomainGlob:  "*",
-		Parallelism: 5,
-		Delay:       50 * time.Millisecond,
-	})
+func newFileCheckResult() *fileCheckResult {
+	return &fileCheckResult{
+		Hashes:           make(map[string]plumbing.Hash),
+		NormalizedHashes: make(map[string]plumbing.Hash),
+		NormalizedBlobs:  make(map[string][]plumbing.Hash),
+		Bodies:           make(map[string][]byte),
+		Encodings:        make(map[string]string),
+		Variants:         make(map[string]string),
+		Statuses:         make(map[string]fileStatus),
+		Evidence:         make(map[string][]fileEvidence),
+	}
+}
+
+// add stores the decoded body of a file found on the webserver. Callers must
+// hold the lock guarding the result, if any.
+func (r *fileCheckResult) add(file string, body []byte, hash plumbing.Hash, options checkOptions) {
+	delete(r.Statuses, file)
+	r.Hashes[file] = hash
+	if len(options.Normalize) > 0 {
+		r.NormalizedHashes[file] = hashBlob(options.Normalize.Apply(body))
+	}
+	if options.KeepBodies {
+		r.Bodies[file] = body
+	}
+}
+
+type checkOptions struct {
+	Rules      rewriteRules
+	Normalize  normalizer
+	KeepBodies bool
+	// Variants probes pre-compressed siblings of files that are not served
+	Variants bool
+}
+
+// checkFileHashes downloads the files from the webserver and hashes them.
+// Files the rewrite rules place outside the webroot are skipped, and
+// responses that look like the server's page for missing files are discarded.
+func checkFileHashes(files []string, baseURI string, options checkOptions) *fileCheckResult {
+	baselines := detectCatchAll(baseURI)
+
+	utils.PrintInfo("Checking files on webserver")
+	c := newCollector(true)
 
 	var (
 		mu sync.Mutex
 		wg sync.WaitGroup
 	)
 
-	fileHashes := make(map[string]plumbing.Hash)
+	result := newFileCheckResult()
+
+	// Rewrite rules may map several files to the same URL, which is only
+	// requested once and attributed to all of them
+	urlFiles := make(map[string][]string)
+	total := 0
+	for _, file := range files {
+		fullURL, ok := fileURL(baseURI, file, options.Rules)
+		if !ok {
+			continue
+		}
+		urlFiles[fullURL] = append(urlFiles[fullURL], file)
+		total++
+	}
+	if skipped := len(files) - total; skipped > 0 {
+		utils.PrintInfo(fmt.Sprintf("Skipping %d files outside the webroot", skipped))
+	}
 
 	prgs := progress.New(
 		progress.WithWidth(40),
@@ -93,7 +176,8 @@ func checkFileHashes(files []string, baseURI string) map[string]plumbing.Hash {
 	)
 	m := &webFetchModel{
 		progress: prgs,
-		total:    len(files),
+		total:    total,
+		statuses: make(map[fileStatus]int),
 	}
 
 	p := tea.NewProgram(m)
@@ -103,50 +187,181 @@ func checkFileHashes(files []string, baseURI string) map[string]plumbing.Hash {
 		}
 	}()
 
+	// request returns false if the request could not be sent, in which case
+	// no callback will run for it
+	request := func(fullURL, variant string) bool {
+		wg.Add(1)
+		ctx := colly.NewContext()
+		ctx.Put("target", fullURL)
+		ctx.Put("variant", variant)
+		ctx.Put("url", fullURL+variant)
+		if err := c.Request("GET", fullURL+variant, nil, ctx, nil); err != nil {
+			wg.Done()
+			return false
+		}
+		return true
+	}
+
+	// fail records why files could not be checked. A failed variant keeps
+	// the status of the file itself.
+	fail := func(files []string, status fileStatus) {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, file := range files {
+			if _, ok := result.Statuses[file]; !ok {
+				result.Statuses[file] = status
+			}
+		}
+	}
+	record := func(files []string, evidence fileEvidence) {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, file := range files {
+			result.Evidence[file] = append(result.Evidence[file], evidence)
+		}
+	}
+	// finish reports files to the progress model, with the status they
+	// ended up with
+	finish := func(files []string) {
+		for _, file := range files {
+			mu.Lock()
+			status := result.Statuses[file]
+			mu.Unlock()
+			p.Send(fileCheckedMsg{
+				status: status,
+			})
+		}
+	}
+
+	// retryVariant requests the next pre-compressed variant of a URL that
+	// was not served, returning false once there is none left. Variants that
+	// are repository files themselves are checked as such.
+	retryVariant := func(ctx *colly.Context) bool {
+		if !options.Variants {
+			return false
+		}
+		target := ctx.Get("target")
+		for next := nextVariant(ctx.Get("variant")); next != ""; next = nextVariant(next) {
+			if len(urlFiles[target+next]) == 0 && request(target, next) {
+				return true
+			}
+		}
+		return false
+	}
+
 	c.OnResponse(func(r *colly.Response) {
 		defer wg.Done()
-		filename := r.Request.Ctx.Get("filename")
-		if filename == "" {
+		files := urlFiles[r.Request.Ctx.Get("target")]
+		if len(files) == 0 {
+			return
+		}
+		variant := r.Request.Ctx.Get("variant")
+		evidence := newEvidence(r, nil)
+
+		encoding, err := decodeResponse(r)
+		if err == nil && variant != "" {
+			r.Body, err = decodeVariant(r.Body, variant, encoding)
+		}
+		if err != nil {
+			evidence.Error = err.Error()
+			record(files, evidence)
+			// A body that fails to decode was most likely cut off in transit
+			fail(files, statusNetworkError)
+			if !retryVariant(r.Request.Ctx) {
+				finish(files)
+			}
 			return
 		}
 
-		hasher := plumbing.NewHasher(plumbing.BlobObject, int64(len(r.Body)))
-		hasher.Write(r.Body)
+		fingerprint := fingerprintResponse(r)
+		evidence.Hash = fingerprint.Hash.String()
+		record(files, evidence)
+		if isCatchAll(baselines, fingerprint) {
+			fail(files, statusSoft404)
+			if retryVariant(r.Request.Ctx) {
+				return
+			}
+			mu.Lock()
+			result.Discarded += len(files)
+			mu.Unlock()
+			finish(files)
+			return
+		}
 
 		mu.Lock()
-		fileHashes[filename] = hasher.Sum()
+		for _, file := range files {
+			result.add(file, r.Body, fingerprint.Hash, options)
+			if encoding != "" {
+				result.Encodings[file] = encoding
+			}
+			if variant != "" {
+				result.Variants[file] = variant
+			}
+		}
 		mu.Unlock()
 
-		p.Send(fileCheckedMsg{
-			error: false,
-		})
+		finish(files)
 	})
 
-	c.OnError(func(_ *colly.Response, err error) {
+	c.OnError(func(r *colly.Response, err error) {
 		defer wg.Done()
-		p.Send(fileCheckedMsg{
-			error: true,
-		})
+		status := errorStatus(r.StatusCode)
+		if shouldRetry(r, err) {
+			wg.Add(1)
+			retryErr := retryRequest(r)
+			if retryErr == nil {
+				return
+			}
+			// No callback runs for a retry that was not sent
+			wg.Done()
+			err = fmt.Errorf("%v, retry not sent: %v", err, retryErr)
+			status = statusNetworkError
+		}
+		files := urlFiles[r.Ctx.Get("target")]
+		record(files, newEvidence(r, err))
+		fail(files, status)
+		if retryVariant(r.Ctx) {
+			return
+		}
+		finish(files)
 	})
 
-	for _, file := range files {
-		fullURL, err := buildFullURL(baseURI, file)
-		if err != nil {
-			continue
+	for fullURL, files := range urlFiles {
+		if !request(fullURL, "") {
+			fail(files, statusNetworkError)
+			finish(files)
 		}
-
-		wg.Add(1)
-		ctx := colly.NewContext()
-		ctx.Put("filename", file)
-		c.Request("GET", fullURL, nil, ctx, nil)
 	}
 
 	wg.Wait()
 	p.Quit()
 
 	utils.PrintInfo("Files checked")
+	if result.Discarded > 0 {
+		utils.PrintInfo(fmt.Sprintf("Discarded %d catch-all responses", result.Discarded))
+	}
+
+	return result
+}
+
+// hashBlob computes the git blob hash of data
+func hashBlob(data []byte) plumbing.Hash {
+	hasher := plumbing.NewHasher(plumbing.BlobObject, int64(len(data)))
+	hasher.Write(data)
+	return hasher.Sum()
+}
 
-	return fileHashes
+// fileURL returns the URL a repository file is served at.
+func fileURL(baseURI, file string, rules rewriteRules) (string, bool) {
+	webPath, ok := rules.WebPath(file)
+	if !ok {
+		return "", false
+	}
+	fullURL, err := buildFullURL(baseURI, webPath)
+	if err != nil {
+		return "", false
+	}
+	return fullURL, true
 }
 
 // buildFullURL constructs a valid URL from base and file path
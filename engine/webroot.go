@@ -0,0 +1,174 @@
+package engine
+
+import (
+	"fmt"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/gocolly/colly"
+	"go-find-version/utils"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// webrootCandidates are repository subdirectories commonly used as the
+// document root, tried in addition to the repository root.
+var webrootCandidates = []string{"public", "web", "docroot", "htdocs", "pub", "www", "core"}
+
+const probeFilesPerCandidate = 5
+
+type webrootProbe struct {
+	rules   rewriteRules
+	urls    map[string]string
+	matched int
+}
+
+// detectWebroot requests a few high-signal files under every candidate
+// mapping and returns the rules whose responses match known blobs of the
+// requested paths most often. The profile's own rules are tried first and
+// win ties; user rules are appended to every candidate.
+func detectWebroot(repository *CachedRepo, files []string, baseURI string, profileRules, userRules rewriteRules) (rewriteRules, error) {
+	configured := append(append(rewriteRules{}, profileRules...), userRules...)
+
+	history, err := loadIndex(repository)
+	if err != nil {
+		return configured, err
+	}
+
+	utils.PrintInfo("Detecting webroot")
+
+	var probes []*webrootProbe
+	seen := make(map[string]bool)
+	urls := make(map[string]bool)
+	for _, candidate := range webrootCandidateRules(files, profileRules) {
+		rules := append(append(rewriteRules{}, candidate...), userRules...)
+		key := strings.Join(rules.Specs(), "\n")
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		probe := &webrootProbe{rules: rules, urls: make(map[string]string)}
+		for _, file := range selectProbeFiles(history, files, rules) {
+			if fullURL, ok := fileURL(baseURI, file, rules); ok {
+				probe.urls[file] = fullURL
+				urls[fullURL] = true
+			}
+		}
+		probes = append(probes, probe)
+	}
+
+	hashes := fetchHashes(urls)
+
+	var best *webrootProbe
+	for _, probe := range probes {
+		for file, fullURL := range probe.urls {
+			if hash, ok := hashes[fullURL]; ok && len(history.rangesFor(file, hash)) > 0 {
+				probe.matched++
+			}
+		}
+		if best == nil || probe.matched > best.matched {
+			best = probe
+		}
+	}
+
+	if best == nil || best.matched == 0 {
+		utils.PrintWarning("No candidate webroot served known files, keeping the configured rewrite rules")
+		return configured, nil
+	}
+
+	utils.PrintInfo(fmt.Sprintf("Detected webroot %s (%d of %d probe files matched)", describeRules(best.rules), best.matched, len(best.urls)))
+	return best.rules, nil
+}
+
+// webrootCandidateRules lists the mappings to probe: the profile's rules, the
+// repository root and every candidate subdirectory present in files.
+func webrootCandidateRules(files []string, profileRules rewriteRules) []rewriteRules {
+	candidates := []rewriteRules{profileRules, {}}
+
+	topLevel := make(map[string]bool)
+	for _, file := range files {
+		if dir, _, ok := strings.Cut(file, "/"); ok {
+			topLevel[dir] = true
+		}
+	}
+
+	for _, dir := range webrootCandidates {
+		if !topLevel[dir] {
+			continue
+		}
+		rule, err := parseRewriteRule("strip:" + dir + "/")
+		if err != nil {
+			continue
+		}
+		candidates = append(candidates, rewriteRules{rule})
+	}
+	return candidates
+}
+
+// selectProbeFiles picks servable files below the candidate webroot whose
+// content changed most often, since they are likely to exist in any release.
+func selectProbeFiles(history *blobHistory, files []string, rules rewriteRules) []string {
+	var probeFiles []string
+	for _, file := range files {
+		if _, ok := rules.WebPath(file); !ok {
+			continue
+		}
+		if classifyFile(file) != classServed || len(history.Paths[file]) == 0 {
+			continue
+		}
+		probeFiles = append(probeFiles, file)
+	}
+
+	sort.Slice(probeFiles, func(i, j int) bool {
+		a, b := len(history.Paths[probeFiles[i]]), len(history.Paths[probeFiles[j]])
+		if a != b {
+			return a > b
+		}
+		return probeFiles[i] < probeFiles[j]
+	})
+
+	if len(probeFiles) > probeFilesPerCandidate {
+		probeFiles = probeFiles[:probeFilesPerCandidate]
+	}
+	return probeFiles
+}
+
+func describeRules(rules rewriteRules) string {
+	if len(rules) == 0 {
+		return "at the repository root"
+	}
+	return strings.Join(rules.Specs(), ", ")
+}
+
+// fetchHashes downloads every URL and returns the blob hashes of the
+// successful responses.
+func fetchHashes(urls map[string]bool) map[string]plumbing.Hash {
+	c := newCollector(true)
+
+	var mu sync.Mutex
+	hashes := make(map[string]plumbing.Hash)
+
+	c.OnResponse(func(r *colly.Response) {
+		if _, err := decodeResponse(r); err != nil {
+			return
+		}
+		mu.Lock()
+		hashes[r.Request.Ctx.Get("url")] = hashBlob(r.Body)
+		mu.Unlock()
+	})
+
+	c.OnError(func(r *colly.Response, err error) {
+		if shouldRetry(r, err) {
+			retryRequest(r)
+		}
+	})
+
+	for fullURL := range urls {
+		ctx := colly.NewContext()
+		ctx.Put("url", fullURL)
+		c.Request("GET", fullURL, nil, ctx, nil)
+	}
+	c.Wait()
+
+	return hashes
+}
@@ -11,7 +11,17 @@ import (
 
 func main() {
 	var args utils.Args
-	arg.MustParse(&args)
+	p := arg.MustParse(&args)
+	if args.GitUrl == "" && args.Profile == "" {
+		p.Fail("--git is required unless --profile is given")
+	}
+	if args.LookupPath != "" {
+		if args.LookupSource == "" {
+			p.Fail("--lookup-source is required with --lookup-path")
+		}
+	} else if args.WebsiteUrl == "" && args.Capture == "" && args.Local == "" {
+		p.Fail("--url is required unless --capture or --local is given")
+	}
 	webEnabled := !args.DisableWeb
 
 	if webEnabled {
@@ -1,9 +1,49 @@
 package utils
 
+import "time"
+
 type Args struct {
-	GitUrl             string `arg:"-g,--git,required" help:"Source of git repository."`
-	WebsiteUrl         string `arg:"-u,--url,required" help:"Source of the vulnerable website."`
-	DisableWeb         bool   `arg:"-w,--web" help:"Disables the website."`
-	Port               int    `arg:"-p,--port" default:"8080" help:"Port for the website."`
-	EnumerationGitFile string `arg:"-e,--enumeration-file" help:"Enumeration file."`
+	GitUrl             string        `arg:"-g,--git" help:"Source of git repository. Defaults to the repository of --profile."`
+	WebsiteUrl         string        `arg:"-u,--url" help:"Source of the vulnerable website."`
+	DisableWeb         bool          `arg:"-w,--web" help:"Disables the website."`
+	Port               int           `arg:"-p,--port" default:"8080" help:"Port for the website."`
+	EnumerationGitFile string        `arg:"-e,--enumeration-file" help:"Enumeration file."`
+	Profile            string        `arg:"--profile" help:"Framework profile: drupal, wordpress, joomla, magento, laravel, symfony, typo3, nextcloud or gitlab."`
+	FullScan           bool          `arg:"--full-scan" help:"Enumerate every file in the repository instead of the profile's file list."`
+	Rewrite            []string      `arg:"--rewrite" help:"Rules mapping repository paths to URL paths, applied in order after the profile's: strip:<prefix>, add:<prefix> or regex:<pattern>=<replacement>."`
+	DetectWebroot      bool          `arg:"--detect-webroot" help:"Probe common webroot subdirectories and use the mapping that serves known files."`
+	Normalize          []string      `arg:"--normalize" help:"Normalize responses and blobs before comparing: text, loose, or any of eol, bom, whitespace, trailing-newline."`
+	Fuzzy              bool          `arg:"--fuzzy" help:"Compare files without an exact match against every historical version and use the nearest one as evidence."`
+	CompressedVariants bool          `arg:"--compressed-variants" help:"Request pre-compressed .gz and .br siblings of files that are not served and hash their decompressed content."`
+	HeadPrefilter      bool          `arg:"--head-prefilter" help:"Send HEAD requests first and only download files whose Content-Length or ETag size matches a known version."`
+	Capture            string        `arg:"--capture" help:"Read responses from a HAR file, a Burp Suite XML export or a mirrored directory instead of requesting them. Directory paths are relative to --url."`
+	Local              string        `arg:"--local" help:"Hash the files of a deployment directory holding the repository instead of requesting them, and list where it differs from the source commit."`
+	Include            []string      `arg:"--include" help:"Only check repository paths matching these gitignore-style patterns."`
+	Exclude            []string      `arg:"--exclude" help:"Skip repository paths matching these gitignore-style patterns. Defaults to *.vue and *.ts."`
+	IncludeFile        string        `arg:"--include-file" help:"File with one include pattern per line."`
+	ExcludeFile        string        `arg:"--exclude-file" help:"File with one exclude pattern per line."`
+	ServableOnly       bool          `arg:"--servable-only" help:"Only check files a webserver likely returns verbatim, skipping executed and blocked ones."`
+	Concurrency        int           `arg:"--concurrency" default:"5" help:"Maximum number of parallel requests to the webserver."`
+	Delay              time.Duration `arg:"--delay" default:"50ms" help:"Pause after every request, per parallel slot."`
+	Jitter             time.Duration `arg:"--jitter" help:"Random extra pause of up to this duration after every request."`
+	RequestsPerSecond  float64       `arg:"--rps" help:"Cap on requests per second across all parallel requests."`
+	UserAgent          string        `arg:"--user-agent" default:"FileChecker/1.0" help:"User agent sent with every request."`
+	Headers            []string      `arg:"-H,--header" help:"Extra header sent with every request, as \"Name: value\". Repeatable."`
+	Timeout            time.Duration `arg:"--timeout" default:"10s" help:"Timeout of a single request."`
+	Retries            int           `arg:"--retries" default:"2" help:"Retries for timeouts, connection errors, 429 and 5xx responses."`
+	RetryBackoff       time.Duration `arg:"--retry-backoff" default:"1s" help:"Pause before the first retry, doubled for every further one."`
+	Proxy              string        `arg:"--proxy" help:"Proxy for all requests to the webserver: http://, https:// or socks5://[user:pass@]host:port."`
+	CABundle           string        `arg:"--ca-cert" help:"PEM file with additional CA certificates to trust."`
+	Insecure           bool          `arg:"-k,--insecure" help:"Skip TLS certificate verification."`
+	ClientCert         string        `arg:"--client-cert" help:"PEM client certificate for mutual TLS."`
+	ClientKey          string        `arg:"--client-key" help:"PEM key of the client certificate. Defaults to --client-cert."`
+	BasicAuth          string        `arg:"--basic-auth" help:"HTTP basic auth credentials as user:password."`
+	BearerToken        string        `arg:"--bearer-token" help:"Bearer token sent in the Authorization header."`
+	CookieFile         string        `arg:"--cookie-file" help:"Cookies to send, in Netscape cookies.txt format or as a \"name=value; ...\" line."`
+	Offline            bool          `arg:"--offline" help:"Use the cached repository as-is instead of fetching new commits and tags, and skip the GitHub API."`
+	Forge              string        `arg:"--forge" help:"Web UI used for commit links: github, gitlab, gitea, bitbucket, cgit or none. Detected from --forge-url or the repository URL by default."`
+	ForgeUrl           string        `arg:"--forge-url" help:"Base URL of the repository's web UI, for self-hosted mirrors and local clones."`
+	Output             string        `arg:"-o,--output" help:"Write a JSON report of the results to this file."`
+	LookupPath         string        `arg:"--lookup-path" help:"Repository path to look up instead of scanning a website."`
+	LookupSource       string        `arg:"--lookup-source" help:"Local file or URL whose content is looked up at --lookup-path."`
 }